@@ -0,0 +1,333 @@
+package simgo
+
+import (
+	"github.com/juju/errgo"
+)
+
+// A Chan is a typed channel between processes, like a Go channel but in
+// simulation time.  Processes send and receive by yielding the events
+// returned by Send() and Recv(), or wait for the first of several operations
+// with ProcComm.Select().
+//
+// A Chan holds up to its capacity of values: sends complete right away while
+// there is room, and otherwise wait for a receiver, which makes an unbuffered
+// Chan (of capacity 0) hand each value off from a sender to a receiver.
+// Waiting operations are served in the order they were made, and handoffs
+// happen at the simulated instant both sides are there, so runs are
+// deterministic: the events of the operations are processed in the order of
+// their event IDs.
+type Chan[T any] struct {
+	env      *Environment
+	capacity int
+	buf      []T
+	sends    []*chanOp[T]
+	recvs    []*chanOp[T]
+	closed   bool
+}
+
+// Received is the value of the event of a receive: the value received, and
+// whether it was sent (rather than the zero value of a closed channel).
+type Received[T any] struct {
+	Value T
+	OK    bool
+}
+
+// chanOp is a pending send or receive.
+type chanOp[T any] struct {
+	event *Event
+	// value is the value sent
+	value T
+	// sel is the Select() the operation is a case of, if any, and index is
+	// the index of the case
+	sel   *selection
+	index int
+}
+
+// NewChan returns a new Chan that holds up to capacity values.
+func NewChan[T any](env *Environment, capacity int) *Chan[T] {
+	return &Chan[T]{env: env, capacity: capacity}
+}
+
+// Send returns an event that is triggered once v has been sent, i.e., once it
+// is in the channel's buffer or has been handed off to a receiver.  The event
+// fails if the channel is closed while the send is waiting.  Send panics if
+// the channel is closed, like sending on a closed Go channel.
+func (c *Chan[T]) Send(v T) *Event {
+	op := &chanOp[T]{event: NewEvent(c.env, WithName("Chan.Send")), value: v}
+	c.send(op)
+	return op.event
+}
+
+// Recv returns an event that is triggered with a Received[T] once a value has
+// been received, or once the channel is closed and empty.
+func (c *Chan[T]) Recv() *Event {
+	op := &chanOp[T]{event: NewEvent(c.env, WithName("Chan.Recv"))}
+	c.recv(op)
+	return op.event
+}
+
+// Close closes the channel: the values in its buffer can still be received,
+// and then receives get the zero value.  Close panics if the channel is
+// already closed.
+func (c *Chan[T]) Close() {
+	if c.closed {
+		panic("simgo: close of closed channel")
+	}
+	c.closed = true
+	c.dispatch()
+}
+
+// Cancel withdraws a pending Send() or Recv() (e.g., after a timeout) and
+// returns whether it was pending.
+func (c *Chan[T]) Cancel(e *Event) bool {
+	for _, ops := range []*[]*chanOp[T]{&c.sends, &c.recvs} {
+		for _, op := range *ops {
+			if op.event == e && op.sel == nil {
+				return removeOp(ops, op)
+			}
+		}
+	}
+	return false
+}
+
+// Len returns the number of values in the channel's buffer.
+func (c *Chan[T]) Len() int {
+	return len(c.buf)
+}
+
+// Cap returns the capacity of the channel.
+func (c *Chan[T]) Cap() int {
+	return c.capacity
+}
+
+// SendCase returns a case of Select() that sends v on the channel.  The value
+// of the case is nil, or an error if the channel was closed while the case
+// was waiting.
+func (c *Chan[T]) SendCase(v T) SelectCase {
+	return sendCase[T]{c, v}
+}
+
+// RecvCase returns a case of Select() that receives from the channel.  The
+// value of the case is a Received[T].
+func (c *Chan[T]) RecvCase() SelectCase {
+	return recvCase[T]{c}
+}
+
+// send adds a send operation.
+func (c *Chan[T]) send(op *chanOp[T]) {
+	if c.closed {
+		panic("simgo: send on closed channel")
+	}
+	c.sends = append(c.sends, op)
+	c.dispatch()
+}
+
+// recv adds a receive operation.
+func (c *Chan[T]) recv(op *chanOp[T]) {
+	c.recvs = append(c.recvs, op)
+	c.dispatch()
+}
+
+// dispatch completes the pending operations that can be completed, in order.
+func (c *Chan[T]) dispatch() {
+	for {
+		// Only a handoff between operations that can be paired is
+		// possible: the cases of a single Select() wait for others, or for
+		// the channel to be closed.
+		var r, s *chanOp[T]
+		if len(c.recvs) > 0 && len(c.sends) > 0 {
+			r, s = c.pair()
+		}
+		switch {
+		case len(c.buf) > 0 && len(c.recvs) > 0:
+			r := c.recvs[0]
+			c.recvs = c.recvs[1:]
+			v := c.buf[0]
+			c.buf = c.buf[1:]
+			complete(r, Received[T]{v, true})
+		case len(c.buf) < c.capacity && len(c.sends) > 0:
+			s := c.sends[0]
+			c.sends = c.sends[1:]
+			c.buf = append(c.buf, s.value)
+			complete(s, nil)
+		case r != nil:
+			removeOp(&c.recvs, r)
+			removeOp(&c.sends, s)
+			complete(r, Received[T]{s.value, true})
+			complete(s, nil)
+		case c.closed && len(c.recvs) > 0:
+			r := c.recvs[0]
+			c.recvs = c.recvs[1:]
+			complete(r, Received[T]{})
+		case c.closed && len(c.sends) > 0:
+			s := c.sends[0]
+			c.sends = c.sends[1:]
+			err := errgo.New("send on closed channel")
+			if s.sel != nil {
+				s.sel.finish(s.index, err)
+			} else {
+				s.event.Fail(err)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// pair returns the first receive and send that can be paired for a handoff,
+// i.e., that are not cases of the same Select(), or nils if there are none.
+func (c *Chan[T]) pair() (*chanOp[T], *chanOp[T]) {
+	for _, r := range c.recvs {
+		for _, s := range c.sends {
+			if r.sel == nil || r.sel != s.sel {
+				return r, s
+			}
+		}
+	}
+	return nil, nil
+}
+
+// complete triggers the event of a completed operation with the provided
+// value.
+func complete[T any](op *chanOp[T], val interface{}) {
+	if op.sel != nil {
+		op.sel.finish(op.index, val)
+		return
+	}
+	op.event.Succeed(val)
+}
+
+// removeOp removes an operation from a list and returns whether it was there.
+func removeOp[T any](ops *[]*chanOp[T], op *chanOp[T]) bool {
+	for i, o := range *ops {
+		if o == op {
+			*ops = append((*ops)[:i], (*ops)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// A SelectCase is a case of ProcComm.Select(): see Chan.SendCase(),
+// Chan.RecvCase() and EventCase().
+type SelectCase interface {
+	// environment returns the Environment of the case
+	environment() *Environment
+	// register adds the case to the selection as the case of the provided
+	// index, and returns a function that withdraws it
+	register(s *selection, index int) func()
+}
+
+// sendCase is a SelectCase that sends a value on a channel.
+type sendCase[T any] struct {
+	c *Chan[T]
+	v T
+}
+
+func (sc sendCase[T]) environment() *Environment {
+	return sc.c.env
+}
+
+func (sc sendCase[T]) register(s *selection, index int) func() {
+	op := &chanOp[T]{event: s.event, value: sc.v, sel: s, index: index}
+	cancel := func() {
+		removeOp(&sc.c.sends, op)
+	}
+	sc.c.send(op)
+	return cancel
+}
+
+// recvCase is a SelectCase that receives from a channel.
+type recvCase[T any] struct {
+	c *Chan[T]
+}
+
+func (rc recvCase[T]) environment() *Environment {
+	return rc.c.env
+}
+
+func (rc recvCase[T]) register(s *selection, index int) func() {
+	op := &chanOp[T]{event: s.event, sel: s, index: index}
+	cancel := func() {
+		removeOp(&rc.c.recvs, op)
+	}
+	rc.c.recv(op)
+	return cancel
+}
+
+// EventCase returns a case of Select() that is ready once the provided event
+// has been processed (e.g., a Timeout).  The value of the case is the value
+// of the event.
+func EventCase(e *Event) SelectCase {
+	return eventCase{e}
+}
+
+// eventCase is a SelectCase that waits for an event.
+type eventCase struct {
+	e *Event
+}
+
+func (ec eventCase) environment() *Environment {
+	return ec.e.env
+}
+
+func (ec eventCase) register(s *selection, index int) func() {
+	ready := func(e *Event) {
+		if !s.done {
+			val, _ := e.Value()
+			s.finish(index, val)
+		}
+	}
+	if ec.e.Processed() {
+		ready(ec.e)
+	} else {
+		ec.e.AddCallback(ready)
+	}
+	return func() {}
+}
+
+// selection is the state of a Select() call.
+type selection struct {
+	event *Event
+	// done is whether a case has been selected
+	done bool
+	// cancels withdraw the cases registered so far
+	cancels []func()
+}
+
+// selected is the value of the event of a Select() call.
+type selected struct {
+	index int
+	value interface{}
+}
+
+// finish selects the case of the provided index, withdraws the other cases,
+// and triggers the event of the selection.
+func (s *selection) finish(index int, val interface{}) {
+	s.done = true
+	for _, cancel := range s.cancels {
+		cancel()
+	}
+	s.event.Succeed(selected{index, val})
+}
+
+// Select suspends the process until one of the cases is ready, performs it,
+// and returns its index and value (see the functions that return cases).
+// Only the selected case is performed: the others are withdrawn at the
+// simulated instant the selected one is ready.  If several cases are ready
+// when Select() is called, the first one is selected.  Select panics if there
+// are no cases.
+func (pc *ProcComm) Select(cases ...SelectCase) (int, interface{}) {
+	if len(cases) == 0 {
+		panic("simgo: Select with no cases")
+	}
+	s := &selection{event: NewEvent(cases[0].environment(), WithName("Select"))}
+	for i, c := range cases {
+		if s.done {
+			break
+		}
+		s.cancels = append(s.cancels, c.register(s, i))
+	}
+	v := pc.Yield(s.event).(selected)
+	return v.index, v.value
+}
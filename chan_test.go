@@ -0,0 +1,197 @@
+package simgo
+
+import (
+	"reflect"
+	"testing"
+)
+
+// goProc starts a process running fn.
+func goProc(env *Environment, fn func(pc *ProcComm)) *Process {
+	p := NewProcess(env, ProcWrapper(env, func(env *Environment, pc *ProcComm) interface{} {
+		fn(pc)
+		return nil
+	}))
+	p.Init()
+	return p
+}
+
+// wait suspends the process for the provided delay.
+func wait(env *Environment, pc *ProcComm, delay uint64) {
+	to := NewTimeout(env, delay, nil)
+	to.Schedule(env)
+	pc.Yield(to.Event)
+}
+
+func TestChanUnbuffered(t *testing.T) {
+	env := NewEnvironment()
+	c := NewChan[int](env, 0)
+	var log []interface{}
+	goProc(env, func(pc *ProcComm) {
+		for i := 0; i < 3; i++ {
+			pc.Yield(c.Send(i))
+			log = append(log, "sent", i, env.Now)
+		}
+		c.Close()
+	})
+	goProc(env, func(pc *ProcComm) {
+		for {
+			wait(env, pc, 10)
+			r := pc.Yield(c.Recv()).(Received[int])
+			if !r.OK {
+				log = append(log, "closed", env.Now)
+				return
+			}
+			log = append(log, "received", r.Value, env.Now)
+		}
+	})
+	env.Run(nil)
+	// Each send waits for the receiver.
+	want := []interface{}{
+		"received", 0, uint64(10), "sent", 0, uint64(10),
+		"received", 1, uint64(20), "sent", 1, uint64(20),
+		"received", 2, uint64(30), "sent", 2, uint64(30),
+		"closed", uint64(40),
+	}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want: %v", log, want)
+	}
+}
+
+func TestChanBuffered(t *testing.T) {
+	env := NewEnvironment()
+	c := NewChan[string](env, 2)
+	var sent []uint64
+	goProc(env, func(pc *ProcComm) {
+		for _, v := range []string{"spam", "eggs", "ham"} {
+			pc.Yield(c.Send(v))
+			sent = append(sent, env.Now)
+		}
+		c.Close()
+	})
+	var received []string
+	goProc(env, func(pc *ProcComm) {
+		wait(env, pc, 10)
+		for {
+			r := pc.Yield(c.Recv()).(Received[string])
+			if !r.OK {
+				return
+			}
+			received = append(received, r.Value)
+		}
+	})
+	env.Run(nil)
+	if want := []uint64{0, 0, 10}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("sent = %v, want: %v", sent, want)
+	}
+	if want := []string{"spam", "eggs", "ham"}; !reflect.DeepEqual(received, want) {
+		t.Errorf("received = %v, want: %v", received, want)
+	}
+}
+
+func TestChanClosedSend(t *testing.T) {
+	env := NewEnvironment()
+	c := NewChan[int](env, 0)
+	send := c.Send(1)
+	c.Close()
+	env.Run(nil)
+	if send.OK() {
+		t.Errorf("send.OK() = true, want: false once closed")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("c.Send() did not panic on a closed channel")
+		}
+	}()
+	c.Send(2)
+}
+
+func TestSelect(t *testing.T) {
+	env := NewEnvironment()
+	a := NewChan[int](env, 0)
+	b := NewChan[string](env, 0)
+	goProc(env, func(pc *ProcComm) {
+		wait(env, pc, 5)
+		pc.Yield(b.Send("spam"))
+		wait(env, pc, 5)
+		pc.Yield(a.Send(42))
+	})
+	var log []interface{}
+	goProc(env, func(pc *ProcComm) {
+		for i := 0; i < 3; i++ {
+			to := NewTimeout(env, 8, "timeout")
+			to.Schedule(env)
+			index, val := pc.Select(a.RecvCase(), b.RecvCase(), EventCase(to.Event))
+			log = append(log, index, val, env.Now)
+		}
+	})
+	env.Run(nil)
+	want := []interface{}{
+		1, Received[string]{"spam", true}, uint64(5),
+		0, Received[int]{42, true}, uint64(10),
+		2, "timeout", uint64(18),
+	}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want: %v", log, want)
+	}
+	// The cases that were not selected were withdrawn.
+	if len(a.recvs) != 0 || len(b.recvs) != 0 {
+		t.Errorf("pending receives: %d and %d, want: none", len(a.recvs), len(b.recvs))
+	}
+}
+
+func TestSelectReady(t *testing.T) {
+	env := NewEnvironment()
+	a := NewChan[int](env, 1)
+	b := NewChan[int](env, 1)
+	a.Send(1)
+	b.Send(2)
+	var index int
+	goProc(env, func(pc *ProcComm) {
+		// Both are ready, so the first case is selected, and only it is
+		// performed.
+		index, _ = pc.Select(b.RecvCase(), a.RecvCase())
+	})
+	env.Run(nil)
+	if index != 0 || a.Len() != 1 || b.Len() != 0 {
+		t.Errorf("index = %d, a.Len() = %d, b.Len() = %d, want: 0, 1, 0", index, a.Len(), b.Len())
+	}
+
+	// A Select does not hand off to itself.
+	c := NewChan[int](env, 0)
+	var got []interface{}
+	goProc(env, func(pc *ProcComm) {
+		index, val := pc.Select(c.SendCase(7), c.RecvCase())
+		got = append(got, index, val)
+	})
+	goProc(env, func(pc *ProcComm) {
+		wait(env, pc, 1)
+		pc.Yield(c.Recv())
+	})
+	env.Run(nil)
+	if want := []interface{}{0, nil}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Select() = %v, want: %v", got, want)
+	}
+}
+
+func TestSelectClosed(t *testing.T) {
+	env := NewEnvironment()
+	c := NewChan[int](env, 0)
+	var got []interface{}
+	goProc(env, func(pc *ProcComm) {
+		// The cases cannot be paired with each other, so the Select waits
+		// until the channel is closed.
+		index, val := pc.Select(c.SendCase(1), c.RecvCase())
+		got = append(got, index, val, env.Now)
+	})
+	goProc(env, func(pc *ProcComm) {
+		wait(env, pc, 5)
+		c.Close()
+	})
+	env.Run(nil)
+	if want := []interface{}{1, Received[int]{}, uint64(5)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Select() = %v, want: %v", got, want)
+	}
+	if len(c.sends) != 0 || len(c.recvs) != 0 {
+		t.Errorf("pending: %d sends and %d receives, want: none", len(c.sends), len(c.recvs))
+	}
+}
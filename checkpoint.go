@@ -0,0 +1,178 @@
+package simgo
+
+import (
+	"container/heap"
+	"encoding/json"
+	"io"
+	"sort"
+
+	"github.com/juju/errgo"
+)
+
+// A Snapshotter is a model whose state can be saved in a checkpoint.
+// Process functions run as goroutines and cannot be saved, so the snapshot
+// must hold enough state (counters, random stream states, the items of its
+// stores, where each process is in its loop, ...) for a RestoreFunc to
+// rebuild the model.  Stores are saved with Store.Snapshot() and rebuilt with
+// Store.Restore().
+type Snapshotter interface {
+	// Snapshot returns the model state.
+	Snapshot() ([]byte, error)
+}
+
+// A RestoreFunc rebuilds a model in env from a snapshot returned by
+// Snapshotter.Snapshot().
+//
+// The RestoreFunc recreates the model's processes and the events they were
+// waiting for.  Each event scheduled while restoring is matched to the first
+// unmatched checkpointed event of the same kind and name, and takes over its
+// time, priority and ID, so any delay can be used when scheduling it.  Naming
+// events with WithName() keeps the matching unambiguous.  Events that are
+// not scheduled but only triggered by other processes (e.g., a plain Event
+// that a process waits for) must be recreated and linked by the RestoreFunc.
+//
+// Initialize events of restored processes that do not match a checkpointed
+// event are processed before Restore() returns, at the checkpoint time and
+// without affecting event IDs, so the processes can yield the events they
+// were waiting for.  Any other unmatched event is an error.
+type RestoreFunc func(env *Environment, snapshot []byte) error
+
+// checkpoint is the serialized state of an Environment.
+type checkpoint struct {
+	Now   uint64            `json:"now"`
+	EID   EventID           `json:"eid"`
+	Queue []checkpointEntry `json:"queue"`
+	Model []byte            `json:"model"`
+}
+
+// checkpointEntry is a scheduled event in a checkpoint.
+type checkpointEntry struct {
+	Time     uint64    `json:"time"`
+	Priority int       `json:"priority"`
+	EID      EventID   `json:"eid"`
+	Kind     EventKind `json:"kind"`
+	Name     string    `json:"name"`
+}
+
+// Checkpoint writes the state of the Environment (the current time, the
+// event ID counter and the scheduled events) and the model's snapshot to w,
+// so the simulation can be continued with Restore().  It must be called
+// between calls to Run() or Step().  The model may be nil.
+//
+// Observers and events that are not scheduled are not saved.
+func (env *Environment) Checkpoint(w io.Writer, model Snapshotter) error {
+	cp, err := env.checkpoint(model)
+	if err != nil {
+		return err
+	}
+	return errgo.Mask(json.NewEncoder(w).Encode(cp))
+}
+
+// Restore reads a checkpoint written by Checkpoint() from r and returns a new
+// Environment in the checkpointed state, with the model rebuilt by the
+// provided RestoreFunc.  Running the returned Environment produces the same
+// results as running the checkpointed one, provided the model is restored
+// faithfully.
+func Restore(r io.Reader, restore RestoreFunc) (*Environment, error) {
+	var cp checkpoint
+	if err := json.NewDecoder(r).Decode(&cp); err != nil {
+		return nil, errgo.Notef(err, "cannot decode checkpoint")
+	}
+	return restoreCheckpoint(&cp, restore)
+}
+
+// checkpoint returns the checkpoint of the Environment and model.
+func (env *Environment) checkpoint(model Snapshotter) (*checkpoint, error) {
+	if env.ActiveProcess != nil {
+		return nil, errgo.New("cannot checkpoint while a process is active")
+	}
+	cp := &checkpoint{
+		Now:   env.Now,
+		EID:   env.eid,
+		Queue: make([]checkpointEntry, 0, len(env.queue)),
+	}
+	if model != nil {
+		var err error
+		if cp.Model, err = model.Snapshot(); err != nil {
+			return nil, errgo.Notef(err, "cannot snapshot model")
+		}
+	}
+	for _, item := range env.queue {
+		cp.Queue = append(cp.Queue, checkpointEntry{
+			Time:     item.time,
+			Priority: item.priority,
+			EID:      item.eid,
+			Kind:     item.kind,
+			Name:     item.name,
+		})
+	}
+	// Match events in the order they were scheduled.
+	sort.Slice(cp.Queue, func(i, j int) bool { return cp.Queue[i].EID < cp.Queue[j].EID })
+	return cp, nil
+}
+
+// restoreCheckpoint returns a new Environment in the checkpointed state.
+func restoreCheckpoint(cp *checkpoint, restore RestoreFunc) (*Environment, error) {
+	env := NewEnvironment()
+	env.Now = cp.Now
+	rs := &restoreState{
+		entries: cp.Queue,
+		matched: make([]bool, len(cp.Queue)),
+	}
+	env.restoring = rs
+	defer func() {
+		env.restoring = nil
+	}()
+	if err := restore(env, cp.Model); err != nil {
+		return nil, errgo.Notef(err, "cannot restore model")
+	}
+	for rs.err == nil && rs.bootstrap.Len() > 0 {
+		heap.Pop(&rs.bootstrap).(*eventQueueItem).process()
+	}
+	if rs.err != nil {
+		return nil, rs.err
+	}
+	for i, entry := range rs.entries {
+		if !rs.matched[i] {
+			return nil, errgo.Newf("checkpointed %s %q at %d was not restored", entry.Kind, entry.Name, entry.Time)
+		}
+	}
+	env.eid = cp.EID
+	return env, nil
+}
+
+// restoreState is the state of a checkpoint being restored.
+type restoreState struct {
+	// entries are the checkpointed events, in the order they were
+	// scheduled
+	entries []checkpointEntry
+	// matched is whether each entry has been matched with a restored event
+	matched []bool
+	// bootstrap holds unmatched Initialize events, with IDs from bootEID
+	bootstrap eventQueue
+	bootEID   EventID
+	// err is the first error encountered while restoring
+	err error
+}
+
+// schedule schedules an event while restoring, either as the checkpointed
+// event it matches or as a bootstrap event.
+func (rs *restoreState) schedule(env *Environment, v *Event, priority int, delay uint64) {
+	for i, entry := range rs.entries {
+		if rs.matched[i] || entry.Kind != v.kind || entry.Name != v.name {
+			continue
+		}
+		rs.matched[i] = true
+		env.push(v, entry.Time, entry.Priority, entry.EID)
+		return
+	}
+	if v.kind != KindInitialize || delay != 0 {
+		if rs.err == nil {
+			rs.err = errgo.Newf("restored %s does not match a checkpointed event", v)
+		}
+		return
+	}
+	v.scheduled = true
+	v.at = env.Now
+	heap.Push(&rs.bootstrap, NewEventQueueItem(v, env.Now, priority, rs.bootEID.Next()))
+}
@@ -0,0 +1,125 @@
+package simgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bgmerrell/simgo/random"
+)
+
+// tickModel has processes that tick at random intervals and logs each tick.
+type tickModel struct {
+	env     *Environment
+	streams map[string]*random.Stream
+	ticks   map[string]int
+	log     []string
+}
+
+// tickSnapshot is the snapshot of a tickModel.
+type tickSnapshot struct {
+	Streams map[string][]byte
+	Ticks   map[string]int
+}
+
+func newTickModel(env *Environment, names ...string) *tickModel {
+	m := &tickModel{
+		env:     env,
+		streams: make(map[string]*random.Stream),
+		ticks:   make(map[string]int),
+	}
+	for _, name := range names {
+		m.streams[name] = random.New(1).Named(name)
+		m.start(name, false)
+	}
+	return m
+}
+
+// start starts the ticker with the provided name.  A restored ticker is
+// already waiting for its next tick.
+func (m *tickModel) start(name string, restored bool) {
+	p := NewProcess(m.env, ProcWrapper(m.env, func(env *Environment, pc *ProcComm) interface{} {
+		for {
+			var delay uint64
+			if !restored {
+				delay = 1 + m.streams[name].Delay(random.Exponential(3))
+			}
+			restored = false
+			to := NewTimeout(env, delay, nil, WithName(name))
+			to.Schedule(env)
+			pc.Yield(to.Event)
+			m.ticks[name]++
+			m.log = append(m.log, fmt.Sprintf("%s@%d", name, env.Now))
+		}
+	}, WithName(name)))
+	p.Init()
+}
+
+func (m *tickModel) Snapshot() ([]byte, error) {
+	snap := tickSnapshot{Streams: make(map[string][]byte), Ticks: m.ticks}
+	for name, s := range m.streams {
+		snap.Streams[name], _ = s.MarshalBinary()
+	}
+	return json.Marshal(snap)
+}
+
+// restoreTickModel returns a RestoreFunc that restores the tickers with the
+// provided names into *m.
+func restoreTickModel(m **tickModel, names ...string) RestoreFunc {
+	return func(env *Environment, snapshot []byte) error {
+		var snap tickSnapshot
+		if err := json.Unmarshal(snapshot, &snap); err != nil {
+			return err
+		}
+		*m = &tickModel{env: env, streams: make(map[string]*random.Stream), ticks: snap.Ticks}
+		for _, name := range names {
+			s := &random.Stream{}
+			if err := s.UnmarshalBinary(snap.Streams[name]); err != nil {
+				return err
+			}
+			(*m).streams[name] = s
+			(*m).start(name, true)
+		}
+		return nil
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	env := NewEnvironment()
+	m := newTickModel(env, "a", "b", "c")
+	env.Run(50)
+	var buf bytes.Buffer
+	if err := env.Checkpoint(&buf, m); err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	saved := buf.Bytes()
+	logged := len(m.log)
+	env.Run(200)
+
+	var restored *tickModel
+	renv, err := Restore(bytes.NewReader(saved), restoreTickModel(&restored, "a", "b", "c"))
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if renv.Now != 50 {
+		t.Errorf("renv.Now = %d, want: 50", renv.Now)
+	}
+	renv.Run(200)
+	if !reflect.DeepEqual(restored.log, m.log[logged:]) {
+		t.Errorf("restored.log = %v, want: %v", restored.log, m.log[logged:])
+	}
+	if !reflect.DeepEqual(restored.ticks, m.ticks) {
+		t.Errorf("restored.ticks = %v, want: %v", restored.ticks, m.ticks)
+	}
+	if renv.eid != env.eid {
+		t.Errorf("renv.eid = %d, want: %d", renv.eid, env.eid)
+	}
+
+	// Every checkpointed event must be restored.
+	_, err = Restore(bytes.NewReader(saved), restoreTickModel(&restored, "a", "b"))
+	if err == nil {
+		t.Errorf("err = nil, want: non-nil when a ticker is not restored")
+	}
+}
@@ -0,0 +1,92 @@
+// Package clock provides a clock interface that production code can be
+// written against, with a real implementation backed by the time package and
+// a simulated one backed by a simgo Environment.
+//
+// Code that takes a Clock can then be tested under simulated time: days of
+// retries and backoff run in milliseconds, and always the same way.
+//
+//	env := simgo.NewEnvironment()
+//	clk := clock.NewSim(env, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Millisecond)
+//	p := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+//		return client.CallWithRetries(clk)
+//	}))
+//	p.Init()
+//	env.Run(nil)
+//
+// See Sim for what simulated code can and cannot do.
+package clock
+
+import (
+	"time"
+)
+
+// A Clock tells the time and waits, like the functions of the time package.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// Sleep pauses the caller for at least the duration d.
+	Sleep(d time.Duration)
+	// After waits for the duration to elapse and then sends the current
+	// time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+	// NewTimer returns a Timer that sends the current time on its channel
+	// after at least the duration d.
+	NewTimer(d time.Duration) Timer
+	// NewTicker returns a Ticker that sends the current time on its
+	// channel every period d.  It panics if d is not positive.
+	NewTicker(d time.Duration) Ticker
+}
+
+// A Timer is a single event, like a time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+	// Stop prevents the Timer from firing.  It returns false if the timer
+	// has already expired or been stopped.
+	Stop() bool
+	// Reset changes the timer to expire after the duration d.  It returns
+	// true if the timer had been active.
+	Reset(d time.Duration) bool
+}
+
+// A Ticker delivers ticks at intervals, like a time.Ticker.
+type Ticker interface {
+	// C returns the channel on which the ticks are delivered.
+	C() <-chan time.Time
+	// Stop turns off the ticker.
+	Stop()
+	// Reset stops the ticker and resets its period to the duration d.
+	Reset(d time.Duration)
+}
+
+// Real returns the Clock of the time package.
+func Real() Clock {
+	return realClock{}
+}
+
+// realClock is a Clock backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+func (realClock) NewTicker(d time.Duration) Ticker       { return realTicker{time.NewTicker(d)} }
+
+// realTimer is a Timer backed by a time.Timer.
+type realTimer struct {
+	t *time.Timer
+}
+
+func (rt realTimer) C() <-chan time.Time        { return rt.t.C }
+func (rt realTimer) Stop() bool                 { return rt.t.Stop() }
+func (rt realTimer) Reset(d time.Duration) bool { return rt.t.Reset(d) }
+
+// realTicker is a Ticker backed by a time.Ticker.
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (rt realTicker) C() <-chan time.Time   { return rt.t.C }
+func (rt realTicker) Stop()                 { rt.t.Stop() }
+func (rt realTicker) Reset(d time.Duration) { rt.t.Reset(d) }
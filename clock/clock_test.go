@@ -0,0 +1,115 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bgmerrell/simgo"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// retry calls op until it succeeds, backing off exponentially from one
+// second to one hour between attempts, and returns the number of attempts.
+// It is written against a Clock, as production code would be.
+func retry(clk Clock, op func() bool) int {
+	backoff := time.Second
+	for attempts := 1; ; attempts++ {
+		if op() {
+			return attempts
+		}
+		clk.Sleep(backoff)
+		if backoff *= 2; backoff > time.Hour {
+			backoff = time.Hour
+		}
+	}
+}
+
+// run runs fn in a process until the simulation ends.
+func run(env *simgo.Environment, fn func()) {
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		fn()
+		return nil
+	}))
+	p.Init()
+	env.Run(nil)
+}
+
+func TestSimSleep(t *testing.T) {
+	env := simgo.NewEnvironment()
+	clk := NewSim(env, epoch, time.Millisecond)
+	// The service is down for two days.
+	up := epoch.Add(48 * time.Hour)
+	var attempts int
+	run(env, func() {
+		attempts = retry(clk, func() bool { return !clk.Now().Before(up) })
+	})
+	// 1s + 2s + ... + 2048s (about 68 minutes), then one attempt an hour.
+	if attempts != 60 {
+		t.Errorf("attempts = %d, want: 60", attempts)
+	}
+	if got := clk.Now(); got.Before(up) || got.After(up.Add(time.Hour)) {
+		t.Errorf("clk.Now() = %s, want: within an hour after %s", got, up)
+	}
+	if got, want := env.Now, uint64(clk.Now().Sub(epoch)/time.Millisecond); got != want {
+		t.Errorf("env.Now = %d, want: %d", got, want)
+	}
+}
+
+func TestSimSleepOutsideProcess(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Sleep() did not panic, want: a panic outside of a process")
+		}
+	}()
+	NewSim(simgo.NewEnvironment(), epoch, time.Second).Sleep(time.Second)
+}
+
+func TestSimTimers(t *testing.T) {
+	env := simgo.NewEnvironment()
+	clk := NewSim(env, epoch, time.Second)
+	var ticks []time.Time
+	stopped := clk.NewTimer(10 * time.Minute)
+	after := clk.After(90 * time.Second)
+	run(env, func() {
+		ticker := clk.NewTicker(time.Minute)
+		for i := 0; i < 3; i++ {
+			ticks = append(ticks, clk.Recv(ticker.C()))
+		}
+		ticker.Stop()
+		if !stopped.Stop() {
+			t.Errorf("stopped.Stop() = false, want: true")
+		}
+	})
+	for i, tick := range ticks {
+		if want := epoch.Add(time.Duration(i+1) * time.Minute); !tick.Equal(want) {
+			t.Errorf("ticks[%d] = %s, want: %s", i, tick, want)
+		}
+	}
+	select {
+	case v := <-after:
+		if want := epoch.Add(90 * time.Second); !v.Equal(want) {
+			t.Errorf("<-after = %s, want: %s", v, want)
+		}
+	default:
+		t.Errorf("after is empty, want: a value")
+	}
+	select {
+	case v := <-stopped.C():
+		t.Errorf("<-stopped.C() = %s, want: no value", v)
+	default:
+	}
+	// The Timeout of the stopped timer is still processed, but ignored.
+	if env.Now != 600 {
+		t.Errorf("env.Now = %d, want: 600", env.Now)
+	}
+}
+
+func TestRealClock(t *testing.T) {
+	clk := Real()
+	start := clk.Now()
+	<-clk.After(time.Millisecond)
+	if elapsed := clk.Now().Sub(start); elapsed < time.Millisecond {
+		t.Errorf("elapsed = %s, want: at least 1ms", elapsed)
+	}
+}
@@ -0,0 +1,233 @@
+package clock
+
+import (
+	"time"
+
+	"github.com/bgmerrell/simgo"
+)
+
+// A Sim is a Clock backed by a simgo Environment.  One unit of simulation
+// time is one unit of the clock, and simulation time 0 is the clock's epoch.
+// Durations are rounded up to whole units.
+//
+// Sleep() must be called from a simgo process (directly or from functions it
+// calls): the calling process yields a Timeout through its ProcComm and is
+// resumed once the duration has elapsed in simulation time.  Calling Sleep()
+// from any other goroutine panics, since only processes can wait for
+// simulation time to pass.
+//
+// The channels of timers and tickers (and After()) are fed by the
+// Environment at the simulated instant they fire, and, like those of the time
+// package, hold at most one pending value.  A process must not block on a
+// receive from them, since the Environment (which would feed the channel)
+// waits for the process to yield.  Instead, a process waits for them with
+// Recv(), or polls them with a select that has a default case.  Code running
+// outside of the simulation can receive from them freely, e.g., once Run()
+// has returned.  Events cannot be removed from the Environment, so the
+// Timeouts of stopped timers are still processed (and ignored): Run(nil)
+// returns once they have passed.
+//
+// A Sim is not safe for concurrent use, like the Environment it wraps.
+type Sim struct {
+	env   *simgo.Environment
+	epoch time.Time
+	unit  time.Duration
+	// timers maps channels to the timer or ticker that feeds them
+	timers map[<-chan time.Time]*simTimer
+}
+
+var _ Clock = (*Sim)(nil)
+
+// NewSim returns a new Sim for the provided Environment, whose simulation
+// time 0 is epoch and whose time unit is unit.  It panics if unit is not
+// positive.
+func NewSim(env *simgo.Environment, epoch time.Time, unit time.Duration) *Sim {
+	if unit <= 0 {
+		panic("clock: non-positive unit for NewSim")
+	}
+	return &Sim{
+		env:    env,
+		epoch:  epoch,
+		unit:   unit,
+		timers: make(map[<-chan time.Time]*simTimer),
+	}
+}
+
+// Now returns the time at the current simulation time.
+func (c *Sim) Now() time.Time {
+	return c.epoch.Add(time.Duration(c.env.Now) * c.unit)
+}
+
+// At returns the simulation time at the time t, rounded up.  Times before the
+// epoch are simulation time 0.
+func (c *Sim) At(t time.Time) uint64 {
+	return c.delay(t.Sub(c.epoch))
+}
+
+// Sleep suspends the active process until the duration d has elapsed in
+// simulation time.  It panics if it is not called from a process.
+func (c *Sim) Sleep(d time.Duration) {
+	pc := c.procComm("Sleep")
+	to := simgo.NewTimeout(c.env, c.delay(d), nil, simgo.WithName("clock.Sleep"))
+	to.Schedule(c.env)
+	pc.Yield(to.Event)
+}
+
+// After returns the channel of a new timer that fires after the duration d.
+func (c *Sim) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// NewTimer returns a Timer that fires after the duration d of simulation
+// time.
+func (c *Sim) NewTimer(d time.Duration) Timer {
+	t := &simTimer{clock: c, c: make(chan time.Time, 1)}
+	t.start(c.delay(d))
+	return t
+}
+
+// NewTicker returns a Ticker that fires every period d of simulation time.
+// It panics if d is not positive.
+func (c *Sim) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	t := &simTimer{clock: c, c: make(chan time.Time, 1), period: c.delay(d)}
+	t.start(t.period)
+	return simTicker{t}
+}
+
+// Recv receives a value from the channel of a timer or ticker of this clock,
+// suspending the active process until one is sent.  It panics if it is not
+// called from a process while the channel is empty, or if the channel is not
+// fed by an active timer or ticker.
+func (c *Sim) Recv(ch <-chan time.Time) time.Time {
+	for {
+		select {
+		case v := <-ch:
+			return v
+		default:
+		}
+		t := c.timers[ch]
+		if t == nil {
+			panic("clock: Recv on a channel that is not fed by an active timer or ticker")
+		}
+		// The timer's callback feeds the channel before the process
+		// is resumed.
+		c.procComm("Recv").Yield(t.next)
+	}
+}
+
+// delay returns the duration d in simulation time, rounded up.
+func (c *Sim) delay(d time.Duration) uint64 {
+	if d <= 0 {
+		return 0
+	}
+	return uint64((d + c.unit - 1) / c.unit)
+}
+
+// procComm returns the ProcComm of the active process, or panics if there
+// is none.
+func (c *Sim) procComm(caller string) *simgo.ProcComm {
+	p := c.env.ActiveProcess
+	if p == nil {
+		panic("clock: " + caller + " must be called from a simgo process")
+	}
+	return p.ProcComm()
+}
+
+// simTimer is a Timer or Ticker of a Sim clock.
+type simTimer struct {
+	clock *Sim
+	c     chan time.Time
+	// period is the ticker period, or 0 for a timer
+	period uint64
+	// next is the Timeout of the next firing, if active
+	next *simgo.Event
+	// gen is incremented when the timer is stopped or reset, so that
+	// pending Timeouts are ignored
+	gen    int
+	active bool
+}
+
+// start schedules the next firing after the provided delay.
+func (t *simTimer) start(delay uint64) {
+	t.gen++
+	gen := t.gen
+	name := "clock.Timer"
+	if t.period > 0 {
+		name = "clock.Ticker"
+	}
+	to := simgo.NewTimeout(t.clock.env, delay, nil, simgo.WithName(name))
+	to.AddCallback(func(*simgo.Event) {
+		t.fire(gen)
+	})
+	to.Schedule(t.clock.env)
+	t.next = to.Event
+	t.active = true
+	t.clock.timers[t.c] = t
+}
+
+// fire sends the current time on the channel, unless the firing was stopped
+// or reset, and schedules the next tick of a ticker.
+func (t *simTimer) fire(gen int) {
+	if gen != t.gen {
+		return
+	}
+	select {
+	case t.c <- t.clock.Now():
+	default:
+	}
+	if t.period > 0 {
+		t.start(t.period)
+		return
+	}
+	t.deactivate()
+}
+
+// deactivate marks the timer as stopped.
+func (t *simTimer) deactivate() {
+	t.gen++
+	t.active = false
+	delete(t.clock.timers, t.c)
+}
+
+func (t *simTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *simTimer) Stop() bool {
+	active := t.active
+	t.deactivate()
+	return active
+}
+
+func (t *simTimer) Reset(d time.Duration) bool {
+	active := t.active
+	delay := t.clock.delay(d)
+	if t.period > 0 {
+		t.period = delay
+	}
+	t.start(delay)
+	return active
+}
+
+// simTicker is a Ticker of a Sim clock.
+type simTicker struct {
+	t *simTimer
+}
+
+func (st simTicker) C() <-chan time.Time {
+	return st.t.c
+}
+
+func (st simTicker) Stop() {
+	st.t.Stop()
+}
+
+func (st simTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	st.t.Reset(d)
+}
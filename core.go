@@ -22,12 +22,16 @@ type Environment struct {
 	Now uint64
 	// ActiveProcess is the currently active process
 	ActiveProcess *Process
+	// Observer, if set, is notified as the simulation runs
+	Observer Observer
 	// Event ID counter
 	eid EventID
 	// The list of all currently scheduled events
 	queue eventQueue
 	// shouldStop tells the Environment when it's time to stop running
 	shouldStop bool
+	// restoring holds the checkpoint being restored, if any
+	restoring *restoreState
 }
 
 // NewEnvironment returns an Environment with default values.
@@ -59,9 +63,9 @@ func (env *Environment) Run(until interface{}) (interface{}, error) {
 			return nil, errgo.New(`"until" value must be a number or an *Event`)
 		case *Event:
 			untilEvent = until
-			if untilEvent.callbacks == nil {
+			if untilEvent.Processed() {
 				// "until" event has already been processed.
-				return untilEvent.Value.Get()
+				return untilEvent.Value()
 			}
 		case int, uint64:
 			if intAt, ok := until.(int); ok {
@@ -75,18 +79,20 @@ func (env *Environment) Run(until interface{}) (interface{}, error) {
 			if at <= env.Now {
 				return nil, errgo.Newf(`"until" value (%d) must be greater than the current simulation time (%d)`, at, env.Now)
 			}
-			untilEvent = NewEvent(env)
-			untilEvent.Value.Set(nil)
+			untilEvent = newEvent(env, KindStop, nil)
+			untilEvent.trigger(nil, true)
 			env.Schedule(untilEvent, PriorityUrgent, at-env.Now)
 
 		}
 		untilEvent.callbacks = append(untilEvent.callbacks, env.stopSimulation)
 	}
+	// Allow the simulation to be continued by another call to Run().
+	env.shouldStop = false
 	for !env.shouldStop {
 		env.Step()
 	}
 	if untilEvent != nil {
-		return untilEvent.Value.Get()
+		return untilEvent.Value()
 	}
 	return nil, nil
 }
@@ -101,19 +107,33 @@ func (env *Environment) Step() {
 	}
 	eqItem := item.(*eventQueueItem)
 	env.Now = eqItem.time
+	if env.Observer != nil {
+		env.Observer.EventProcessed(eqItem.Event, eqItem.time, eqItem.priority, eqItem.eid)
+	}
 
 	// Process the event callbacks
-	callbacks := eqItem.callbacks
-	eqItem.callbacks = nil
-	for _, callback := range callbacks {
-		callback(eqItem.Event)
-	}
+	eqItem.process()
 }
 
 // Schedule adds the provided Event to the event priority queue.  A priority
 // and delay for the event is also provided.
 func (env *Environment) Schedule(v *Event, priority int, delay uint64) {
-	heap.Push(&env.queue, NewEventQueueItem(v, env.Now+delay, priority, env.eid.Next()))
+	if env.restoring != nil {
+		env.restoring.schedule(env, v, priority, delay)
+		return
+	}
+	env.push(v, env.Now+delay, priority, env.eid.Next())
+}
+
+// push adds the provided Event to the event priority queue with the provided
+// time, priority and ID.
+func (env *Environment) push(v *Event, at uint64, priority int, eid EventID) {
+	v.scheduled = true
+	v.at = at
+	heap.Push(&env.queue, NewEventQueueItem(v, at, priority, eid))
+	if env.Observer != nil {
+		env.Observer.EventScheduled(v, at, priority, eid)
+	}
 }
 
 // stopSimulation is a special callback that tells the Environment that it's
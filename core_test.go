@@ -0,0 +1,31 @@
+package simgo
+
+import (
+	"testing"
+)
+
+func TestRunContinue(t *testing.T) {
+	env := NewEnvironment()
+	ticks := 0
+	p := NewProcess(env, ProcWrapper(env, func(env *Environment, pc *ProcComm) interface{} {
+		for {
+			to := NewTimeout(env, 1, nil)
+			to.Schedule(env)
+			pc.Yield(to.Event)
+			ticks++
+		}
+	}))
+	p.Init()
+	for _, until := range []int{5, 10} {
+		if _, err := env.Run(until); err != nil {
+			t.Fatalf("err = %s, want: nil", err)
+		}
+		if env.Now != uint64(until) {
+			t.Errorf("env.Now = %d, want: %d", env.Now, until)
+		}
+	}
+	// The timeout at 10 is processed after the (urgent) "until" event.
+	if ticks != 9 {
+		t.Errorf("ticks = %d, want: 9", ticks)
+	}
+}
@@ -2,10 +2,10 @@ package simgo
 
 import (
 	"container/heap"
-	"math/rand"
 	"reflect"
 	"testing"
-	"time"
+
+	"github.com/bgmerrell/simgo/random"
 )
 
 func TestEventQueue(t *testing.T) {
@@ -48,7 +48,7 @@ func BenchmarkPopPush(b *testing.B) {
 		maxTime     = 1000000
 		maxPriority = 5
 	)
-	rand.Seed(time.Now().UnixNano())
+	rng := random.New(1).Named("eventqueue")
 	eq := make(eventQueue, 0)
 	heap.Init(&eq)
 	items := make([]*eventQueueItem, nItems)
@@ -56,8 +56,8 @@ func BenchmarkPopPush(b *testing.B) {
 	// &eventQueueItem{time: 90, priority: 1, eid: 5},  // 0: 3rd
 	for i := 0; i < nItems; i++ {
 		items[i] = &eventQueueItem{
-			time:     uint64(rand.Intn(maxTime)),
-			priority: rand.Intn(maxPriority),
+			time:     uint64(rng.Intn(maxTime)),
+			priority: rng.Intn(maxPriority),
 			eid:      EventID(i),
 		}
 	}
@@ -1,6 +1,7 @@
 package simgo
 
 import (
+	"fmt"
 	"reflect"
 	"runtime"
 	"strings"
@@ -14,37 +15,68 @@ const (
 	PriorityNormal
 )
 
+// An EventKind describes what an Event is used for.
+type EventKind string
+
+const (
+	// Built-in event kinds
+	KindEvent      EventKind = "Event"
+	KindTimeout    EventKind = "Timeout"
+	KindProcess    EventKind = "Process"
+	KindCondition  EventKind = "Condition"
+	KindInitialize EventKind = "Initialize"
+	KindInterrupt  EventKind = "Interrupt"
+	KindStop       EventKind = "StopSimulation"
+)
+
+// An EventOption configures an Event when it is created.
+type EventOption func(*Event)
+
+// WithName returns an EventOption that names an event.  Names are used when
+// an event is formatted (e.g., in errors and traces).
+func WithName(name string) EventOption {
+	return func(e *Event) {
+		e.name = name
+	}
+}
+
 // conditionEvaluateFn is the evaluate function signature used for conditions
 type (
 	conditionEvaluateFn func(events []*Event, count int) bool
 	ConditionValue      []interface{}
 )
 
-// TODO: Move EventValue to its own package or refactor.  Currently, it's too
-// easy to use the struct fields directly when the methods should really be
-// the only things used.
+// eventState is the lifecycle state of an Event
+type eventState int
+
+const (
+	eventPending eventState = iota
+	eventTriggered
+	eventProcessed
+)
 
 // EventValue holds the value state for an Event.  If the value is pending it
-// means that the event
+// means that the event has not yet been triggered.  EventValues can only be
+// read; they are set by triggering their Event.
 type EventValue struct {
 	val       interface{}
 	isPending bool
 }
 
-// NewEventValue returns a pending EventValue.
-func NewEventValue() *EventValue {
+// newEventValue returns a pending EventValue.
+func newEventValue() *EventValue {
 	return &EventValue{nil, true}
 }
 
-// Set sets the underlying value (and sets isPending accordingly).
-func (ev *EventValue) Set(value interface{}) {
+// set sets the underlying value (and sets isPending accordingly).
+func (ev *EventValue) set(value interface{}) {
 	ev.val = value
 	ev.isPending = false
 }
 
-// Add adds an underlying value to a map (and initializes the map and sets
-// isPending accordingly).
-func (ev *EventValue) Add(eventValue *EventValue) {
+// add adds an underlying value to a ConditionValue (and initializes the
+// ConditionValue if needed).
+func (ev *EventValue) add(eventValue *EventValue) {
 	if ev.val == nil {
 		ev.val = ConditionValue{eventValue}
 	} else {
@@ -62,22 +94,31 @@ func (ev *EventValue) Get() (interface{}, error) {
 	return ev.val, err
 }
 
+// String returns the underlying value formatted with the default format, or
+// "pending" if the value is still pending.
+func (ev *EventValue) String() string {
+	if ev.isPending {
+		return "pending"
+	}
+	return fmt.Sprint(ev.val)
+}
+
 // An Event is an event that may happen at some point in time.
 //
 //    An event
 //
-//    - may happen (i.e., triggered is False),
-//    - is going to happen (i.e., triggered True) or
-//    - has happened (i.e., processed True).
+//    - may happen (i.e., Triggered() is false),
+//    - is going to happen (i.e., Triggered() is true) or
+//    - has happened (i.e., Processed() is true).
 //
 // Every event is bound to an environment (env) and is initially not triggered.
 // Events are scheduled for processing by the environment after they are
-// triggered by either `succeed`, `fail` or `trigger`. These methods also set
-// the `ok` flag and the value of the event.
+// triggered by either Succeed(), Fail() or Trigger(). These methods also set
+// the OK() flag and the Value() of the event.
 //
 // An event has a list of `callbacks`. Once an event gets processed, all
 // callbacks will be called with the event as the single argument. Callbacks
-// can check if the event was successful by examining `ok` and do further
+// can check if the event was successful by examining OK() and do further
 // processing with the value it has produced.
 //
 // TODO: Talk about how events are finalized/defused (?) after being processed.
@@ -86,16 +127,126 @@ type Event struct {
 	env *Environment
 	// List of functions that are called when the event is processed.
 	callbacks []func(*Event)
-	// Value holds the event's value
-	Value *EventValue
+	// value holds the event's value
+	value *EventValue
+	// state is whether the event is pending, triggered or processed
+	state eventState
+	// ok is whether the event was triggered successfully
+	ok bool
+	// kind is what the event is used for
+	kind EventKind
+	// name is the optional name of the event
+	name string
+	// scheduled is whether the event has been scheduled
+	scheduled bool
+	// at is the time the event is scheduled for
+	at uint64
 }
 
 // NewEvent returns a new Event object with default values.
-func NewEvent(env *Environment) *Event {
-	return &Event{
-		env,
-		make([]func(*Event), 0),
-		NewEventValue(),
+func NewEvent(env *Environment, opts ...EventOption) *Event {
+	return newEvent(env, KindEvent, opts)
+}
+
+// newEvent returns a new Event object of the provided kind.
+func newEvent(env *Environment, kind EventKind, opts []EventOption) *Event {
+	e := &Event{
+		env:       env,
+		callbacks: make([]func(*Event), 0),
+		value:     newEventValue(),
+		kind:      kind,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Kind returns what the event is used for.  Events created with NewEvent are
+// of kind KindEvent.
+func (e *Event) Kind() EventKind {
+	return e.kind
+}
+
+// Name returns the name of the event, or an empty string if the event was not
+// named.
+func (e *Event) Name() string {
+	return e.name
+}
+
+// String returns the kind, name, scheduled time and state of the event, e.g.:
+//
+//     Timeout "trip" at 7 (triggered)
+func (e *Event) String() string {
+	s := string(e.kind)
+	if e.name != "" {
+		s += fmt.Sprintf(" %q", e.name)
+	}
+	if e.scheduled {
+		s += fmt.Sprintf(" at %d", e.at)
+	}
+	switch e.state {
+	case eventProcessed:
+		s += " (processed)"
+	case eventTriggered:
+		s += " (triggered)"
+	default:
+		s += " (pending)"
+	}
+	return s
+}
+
+// Triggered returns whether the event has been triggered, i.e., whether it is
+// going to happen (or has happened).
+func (e *Event) Triggered() bool {
+	return e.state != eventPending
+}
+
+// Processed returns whether the event has been processed, i.e., whether it has
+// happened and its callbacks have been called.
+func (e *Event) Processed() bool {
+	return e.state == eventProcessed
+}
+
+// OK returns whether the event was triggered successfully.  It returns false
+// if the event failed or has not yet been triggered.
+func (e *Event) OK() bool {
+	return e.ok
+}
+
+// Value returns the value of the event along with an error if the event has
+// not yet been triggered.  The value of a failed event is its error.
+func (e *Event) Value() (interface{}, error) {
+	return e.value.Get()
+}
+
+// AddCallback adds a function that is called with the event when it is
+// processed.  Callbacks are called in the order they were added, along with
+// those that resume the processes waiting for the event.  Returns an error if
+// the event has already been processed.
+func (e *Event) AddCallback(fn func(*Event)) error {
+	if e.Processed() {
+		return errgo.Newf("%s has already been processed", e)
+	}
+	e.callbacks = append(e.callbacks, fn)
+	return nil
+}
+
+// trigger sets the event's value and whether it was successful without
+// scheduling it.
+func (e *Event) trigger(val interface{}, ok bool) {
+	e.value.set(val)
+	e.ok = ok
+	e.state = eventTriggered
+}
+
+// process marks the event as processed and runs its callbacks.
+func (e *Event) process() {
+	callbacks := e.callbacks
+	e.callbacks = nil
+	e.state = eventProcessed
+	for _, callback := range callbacks {
+		callback(e)
 	}
 }
 
@@ -103,40 +254,43 @@ func NewEvent(env *Environment) *Event {
 // processing by the environment. Returns the event instance along with any
 // errors.
 func (e *Event) Succeed(val interface{}) (*Event, error) {
-	if !e.Value.isPending {
+	if e.Triggered() {
 		return e, errgo.Newf("%s has already been triggered", e)
 	}
-	e.Value.Set(val)
+	e.trigger(val, true)
 	e.env.Schedule(e, PriorityNormal, 0)
 	return e, nil
 }
 
-// Fail sets the provided EventValue as the events value, marks the event as
+// Fail sets the provided error as the events value, marks the event as
 // failed, and schedules it for processing by the environment.  The event
 // instance is returned along with any errors.
-func (e *Event) Fail(eventValue *EventValue) (*Event, error) {
-	if !e.Value.isPending {
+func (e *Event) Fail(err error) (*Event, error) {
+	if e.Triggered() {
 		return nil, errgo.Newf("%s has already been triggered", e)
 	}
-
-	errVal, err := eventValue.Get()
-	if err != nil {
-		return nil, err
-	}
-	if _, ok := errVal.(error); ok {
-		e.Value.Set(errVal)
-	} else {
-		return nil, errgo.Newf("%#v is not an error", errVal)
+	if err == nil {
+		return nil, errgo.New("nil is not an error")
 	}
+	e.trigger(err, false)
 	e.env.Schedule(e, PriorityNormal, 0)
 	return e, nil
 }
 
-// isOK returns whether the event is OK, which means that the EventValue is
-// not an error.
-func (e *Event) isOK() bool {
-	_, isErr := e.Value.val.(error)
-	return !isErr
+// Trigger copies the outcome (i.e., the value and whether it was successful)
+// of the provided event, which must already be triggered, and schedules the
+// event for processing by the environment.  The event instance is returned
+// along with any errors.
+func (e *Event) Trigger(other *Event) (*Event, error) {
+	if e.Triggered() {
+		return nil, errgo.Newf("%s has already been triggered", e)
+	}
+	if !other.Triggered() {
+		return nil, errgo.Newf("%s has not been triggered", other)
+	}
+	e.trigger(other.value.val, other.ok)
+	e.env.Schedule(e, PriorityNormal, 0)
+	return e, nil
 }
 
 // Timeout embeds an event and adds a delay
@@ -148,13 +302,11 @@ type Timeout struct {
 // NewTimeout returns a new Timeout object given an environment, delay and an
 // Event value.  The event is automatically triggered when this function is
 // called.
-func NewTimeout(env *Environment, delay uint64, value interface{}) Timeout {
+func NewTimeout(env *Environment, delay uint64, value interface{}, opts ...EventOption) Timeout {
+	e := newEvent(env, KindTimeout, opts)
+	e.trigger(value, true)
 	return Timeout{
-		&Event{
-			env,
-			make([]func(*Event), 0),
-			&EventValue{value, false},
-		},
+		e,
 		delay,
 	}
 }
@@ -174,73 +326,142 @@ type Process struct {
 	*Event
 	env *Environment
 	pc  *ProcComm
+	// started is whether the process function has been run yet
+	started bool
+	// finished is whether the process function has returned
+	finished bool
+	// target is the event the process is waiting for, if any
+	target *Event
+	// detached counts the interrupts and pauses that detached the process
+	// from its target
+	detached int
+	// killed is the cause of the process's death, if it was killed
+	killed *Interrupt
 }
 
 // NewProcess returns a new Process given an Environment and a ProcComm
 // (which is used to communicate between the process function coroutine and the
-// Process).
+// Process).  The Process is configured with the options passed to
+// ProcWrapper().
 func NewProcess(env *Environment, pc *ProcComm) *Process {
 	return &Process{
-		NewEvent(env),
-		env,
-		pc,
+		Event: newEvent(env, KindProcess, pc.opts),
+		env:   env,
+		pc:    pc,
 	}
 }
 
 // Init initializes the process.  The process's Event is automatically
 // triggered and scheduled.
 func (p *Process) Init() {
-	initEvent := NewEvent(p.env)
+	initEvent := newEvent(p.env, KindInitialize, []EventOption{WithName(p.name)})
 	initEvent.callbacks = append(initEvent.callbacks, p.resume)
-	initEvent.Value.Set(nil)
+	initEvent.trigger(nil, true)
 	p.env.Schedule(initEvent, PriorityUrgent, 0)
 }
 
 // resume takes care of resuming the process function with the value of the
 // provided Event.
 func (p *Process) resume(event *Event) {
+	if p.finished {
+		return
+	}
 	p.env.ActiveProcess = p
 	defer func() {
 		p.env.ActiveProcess = nil
 	}()
 	for {
 		// event value is already triggered, no need to check err
-		eventVal, _ := event.Value.Get()
+		eventVal, _ := event.Value()
+		if p.killed != nil {
+			eventVal = killSignal{}
+		}
+		if obs := p.env.Observer; obs != nil {
+			if p.started {
+				obs.ProcessResumed(p, event)
+			} else {
+				obs.ProcessStarted(p)
+			}
+		}
+		p.started = true
 		if nextEvent, ok := p.pc.Resume(eventVal); ok {
 			event = nextEvent
 		} else {
-			// Set the process value to nil if it hasn't been set
-			// already.
-			if p.Event.Value.isPending {
-				p.Event.Value.Set(nil)
+			p.finished = true
+			// Set the process value to nil (or the cause of its death
+			// if it was killed) if it hasn't been set already.
+			if !p.Event.Triggered() {
+				if p.killed != nil {
+					p.Event.trigger(p.killed, false)
+				} else {
+					p.Event.trigger(nil, true)
+				}
+			}
+			if p.env.Observer != nil {
+				p.env.Observer.ProcessFinished(p)
 			}
 			p.env.Schedule(p.Event, PriorityNormal, 0)
 			break
 		}
 
-		if event.callbacks != nil {
-			// The event has not yet been triggered. Register
+		if !event.Processed() {
+			// The event has not yet been processed. Register
 			// callback to resume the process if that happens.
-			event.callbacks = append(event.callbacks, p.resume)
+			p.target = event
+			event.callbacks = append(event.callbacks, p.resumeTarget)
+			if p.env.Observer != nil {
+				p.env.Observer.ProcessSuspended(p, event)
+			}
 			return
 		}
 	}
 }
 
+// resumeTarget resumes the process with the provided event if it is still
+// the event the process is waiting for, i.e., unless the process was
+// interrupted or paused in the meantime.
+func (p *Process) resumeTarget(event *Event) {
+	if event != p.target {
+		return
+	}
+	p.target = nil
+	p.resume(event)
+}
+
 // ReturnValue returns the value returned by the process function.
 func (p *Process) ReturnValue() interface{} {
 	return p.pc.returnValue
 }
 
+// ProcComm returns the ProcComm of the process function, so functions called
+// by the process function can yield events on its behalf (e.g., through
+// env.ActiveProcess).
+func (p *Process) ProcComm() *ProcComm {
+	return p.pc
+}
+
 // ProcWrapper is function that turns a user process function into a coroutine
 // that can suspend its execution by yielding an event (using
 // ProcComm.Yield()).
 //
+// The provided options (e.g., WithName()) configure the Process that is created
+// for the returned ProcComm.
+//
 // See the examples directory for example usage.
 //
-func ProcWrapper(env *Environment, procFn func(*Environment, *ProcComm) interface{}) *ProcComm {
+func ProcWrapper(env *Environment, procFn func(*Environment, *ProcComm) interface{}, opts ...EventOption) *ProcComm {
 	pc := NewProcComm()
+	pc.opts = opts
 	go func() {
+		// A killed process unwinds from Yield() with a killSignal.
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(killSignal); !ok {
+					panic(r)
+				}
+				pc.Finish(nil)
+			}
+		}()
 		// An initial yield imitates coroutine behavior of not
 		// executing the coroutine body upon creation.
 		pc.Yield(nil)
@@ -271,9 +492,9 @@ type Condition struct {
 	count      int
 }
 
-func NewCondition(env *Environment, evaluateFn conditionEvaluateFn, events []*Event) *Condition {
+func NewCondition(env *Environment, evaluateFn conditionEvaluateFn, events []*Event, opts ...EventOption) *Condition {
 	c := &Condition{
-		NewEvent(env),
+		newEvent(env, KindCondition, opts),
 		evaluateFn,
 		events,
 		0,
@@ -301,40 +522,44 @@ func NewCondition(env *Environment, evaluateFn conditionEvaluateFn, events []*Ev
 // check checks if the condition was already met and schedules the event if
 // it was.
 func (c *Condition) check(event *Event) {
-	if !c.Event.Value.isPending {
+	if c.Event.Triggered() {
 		return
 	}
 
 	c.count++
 
-	if !event.isOK() {
+	if !event.OK() {
 		// TODO: Use "Defused?"
 		// c.Event.Defused = true
-		c.Event.Fail(event.Value)
+		c.Event.Trigger(event)
 	} else if c.evaluateFn(c.events, c.count) {
 		// The condition has been met. The buildValue() callback will
 		// populate the ConditionValue once this condition is
 		// processed.
 		c.Event.Succeed(nil)
+	} else {
+		return
+	}
+	if c.env.Observer != nil {
+		c.env.Observer.ConditionTriggered(c, event)
 	}
 }
 
 func (c *Condition) buildValue(event *Event) {
 	c.removeCheckCallbacks()
-	if !c.Event.isOK() {
+	if !c.Event.OK() {
 		return
 	}
 	for _, event := range c.events {
-		if event.callbacks != nil {
-			// Event has not yet been processed
+		if !event.Processed() {
 			continue
 		}
-		if _, ok := event.Value.val.(ConditionValue); ok {
-			for _, val := range event.Value.val.(ConditionValue) {
-				c.Event.Value.Add(val.(*EventValue))
+		if conditionValue, ok := event.value.val.(ConditionValue); ok {
+			for _, val := range conditionValue {
+				c.Event.value.add(val.(*EventValue))
 			}
 		} else {
-			c.Event.Value.Add(event.Value)
+			c.Event.value.add(event.value)
 		}
 	}
 }
@@ -361,18 +586,18 @@ func (c *Condition) removeCheckCallbacks() {
 	}
 }
 
-func AllOf(env *Environment, events []*Event) *Condition {
+func AllOf(env *Environment, events []*Event, opts ...EventOption) *Condition {
 	var evalFn conditionEvaluateFn = func(events []*Event, count int) bool {
 		return len(events) == count
 	}
 
-	return NewCondition(env, evalFn, events)
+	return NewCondition(env, evalFn, events, opts...)
 }
 
-func AnyOf(env *Environment, events []*Event) *Condition {
+func AnyOf(env *Environment, events []*Event, opts ...EventOption) *Condition {
 	var evalFn conditionEvaluateFn = func(events []*Event, count int) bool {
 		return count > 0 || len(events) == 0
 	}
 
-	return NewCondition(env, evalFn, events)
+	return NewCondition(env, evalFn, events, opts...)
 }
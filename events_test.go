@@ -0,0 +1,121 @@
+package simgo
+
+import (
+	"testing"
+
+	"github.com/juju/errgo"
+)
+
+func TestEventString(t *testing.T) {
+	env := NewEnvironment()
+	e := NewEvent(env, WithName("class ends"))
+	if got, want := e.String(), `Event "class ends" (pending)`; got != want {
+		t.Errorf("e.String() = %q, want: %q", got, want)
+	}
+	to := NewTimeout(env, 7, nil)
+	to.Schedule(env)
+	if got, want := to.String(), `Timeout at 7 (triggered)`; got != want {
+		t.Errorf("to.String() = %q, want: %q", got, want)
+	}
+	env.Run(nil)
+	if got, want := to.String(), `Timeout at 7 (processed)`; got != want {
+		t.Errorf("to.String() = %q, want: %q", got, want)
+	}
+
+	_, err := to.Succeed(nil)
+	if err == nil {
+		t.Fatalf("err = nil, want: non-nil")
+	}
+	if got, want := err.Error(), `Timeout at 7 (processed) has already been triggered`; got != want {
+		t.Errorf("err.Error() = %q, want: %q", got, want)
+	}
+}
+
+func TestProcessName(t *testing.T) {
+	env := NewEnvironment()
+	p := NewProcess(env, ProcWrapper(env, func(env *Environment, pc *ProcComm) interface{} {
+		return nil
+	}, WithName("car")))
+	if got, want := p.Name(), "car"; got != want {
+		t.Errorf("p.Name() = %q, want: %q", got, want)
+	}
+	p.Init()
+	env.Run(nil)
+	if got, want := p.String(), `Process "car" at 0 (processed)`; got != want {
+		t.Errorf("p.String() = %q, want: %q", got, want)
+	}
+}
+
+func TestEventLifecycle(t *testing.T) {
+	env := NewEnvironment()
+	e := NewEvent(env)
+	if e.Triggered() || e.Processed() || e.OK() {
+		t.Errorf("Triggered() = %t, Processed() = %t, OK() = %t, want: false, false, false",
+			e.Triggered(), e.Processed(), e.OK())
+	}
+	if _, err := e.Value(); err == nil {
+		t.Errorf("err = nil, want: non-nil")
+	}
+
+	e.Succeed("spam")
+	if !e.Triggered() || e.Processed() || !e.OK() {
+		t.Errorf("Triggered() = %t, Processed() = %t, OK() = %t, want: true, false, true",
+			e.Triggered(), e.Processed(), e.OK())
+	}
+	env.Run(nil)
+	if !e.Triggered() || !e.Processed() || !e.OK() {
+		t.Errorf("Triggered() = %t, Processed() = %t, OK() = %t, want: true, true, true",
+			e.Triggered(), e.Processed(), e.OK())
+	}
+	val, err := e.Value()
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if val != "spam" {
+		t.Errorf("val = %#v, want: %#v", val, "spam")
+	}
+}
+
+func TestEventTrigger(t *testing.T) {
+	env := NewEnvironment()
+	failed := NewEvent(env)
+	e := NewEvent(env)
+	if _, err := e.Trigger(failed); err == nil {
+		t.Errorf("err = nil, want: non-nil")
+	}
+
+	failErr := errgo.New("boom")
+	failed.Fail(failErr)
+	if _, err := e.Trigger(failed); err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if e.OK() {
+		t.Errorf("e.OK() = true, want: false")
+	}
+	if val, _ := e.Value(); val != failErr {
+		t.Errorf("val = %#v, want: %#v", val, failErr)
+	}
+	if _, err := e.Trigger(failed); err == nil {
+		t.Errorf("err = nil, want: non-nil")
+	}
+}
+
+func TestAddCallback(t *testing.T) {
+	env := NewEnvironment()
+	to := NewTimeout(env, 3, "spam")
+	var got []interface{}
+	if err := to.AddCallback(func(e *Event) {
+		val, _ := e.Value()
+		got = append(got, val, env.Now)
+	}); err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	to.Schedule(env)
+	env.Run(nil)
+	if len(got) != 2 || got[0] != "spam" || got[1] != uint64(3) {
+		t.Errorf("got = %v, want: [spam 3]", got)
+	}
+	if err := to.AddCallback(func(*Event) {}); err == nil {
+		t.Errorf("err = nil, want: non-nil for a processed event")
+	}
+}
@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/bgmerrell/simgo"
 )
 
+// out is where the example prints its output.  Tests replace it.
+var out io.Writer = os.Stdout
+
 // simpy example:
 /*
 import simpy
@@ -28,30 +33,46 @@ env.active_process  # None
 
 // Output:
 /*
-<Process(my_proc) object at 0x7f045064dac8>
-<Process(my_proc) object at 0x7f045064dac8>
+None
+my_proc
+my_proc
+None
 */
 
+// printActiveProcess prints the name of the active process, or None.
+func printActiveProcess(env *simgo.Environment) {
+	if env.ActiveProcess == nil {
+		fmt.Fprintln(out, "None")
+		return
+	}
+	fmt.Fprintln(out, env.ActiveProcess.Name())
+}
+
 func subfunc(env *simgo.Environment) {
-	fmt.Printf("%p\n", env.ActiveProcess)
+	printActiveProcess(env)
 }
 
 func myProc(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 	for {
-		fmt.Printf("%p\n", env.ActiveProcess)
+		printActiveProcess(env)
 		subfunc(env)
 		to := simgo.NewTimeout(env, 1, nil)
 		to.Schedule(env)
 		pc.Yield(to.Event)
 	}
-	return nil
+}
+
+// start starts the example's process.
+func start(env *simgo.Environment) *simgo.Process {
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, myProc, simgo.WithName("my_proc")))
+	p.Init()
+	return p
 }
 
 func main() {
 	env := simgo.NewEnvironment()
-	p := simgo.NewProcess(env, simgo.ProcWrapper(env, myProc))
-	p.Init()
-	fmt.Printf("%p\n", env.ActiveProcess)
+	start(env)
+	printActiveProcess(env)
 	env.Step()
-	fmt.Printf("%p\n", env.ActiveProcess)
+	printActiveProcess(env)
 }
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/simtest"
+)
+
+func TestActiveProc(t *testing.T) {
+	var buf bytes.Buffer
+	out = &buf
+	simtest.AssertGolden(t, "active_proc", func(env *simgo.Environment, rng *random.Stream) {
+		start(env)
+		printActiveProcess(env)
+		env.Step()
+		printActiveProcess(env)
+	}, 0)
+	want := "None\nmy_proc\nmy_proc\nNone\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
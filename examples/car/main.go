@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/bgmerrell/simgo"
 )
 
+// out is where the example prints its output.  Tests replace it.
+var out io.Writer = os.Stdout
+
 // simpy example:
 /*
 def car(env):
@@ -39,22 +44,28 @@ func car(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 		trip_duration    = 2
 	)
 	for {
-		fmt.Printf("Start parking at %d\n", env.Now)
+		fmt.Fprintf(out, "Start parking at %d\n", env.Now)
 
 		to := simgo.NewTimeout(env, parking_duration, nil)
 		to.Schedule(env)
 		pc.Yield(to.Event)
 
-		fmt.Printf("Start driving at %d\n", env.Now)
+		fmt.Fprintf(out, "Start driving at %d\n", env.Now)
 		to = simgo.NewTimeout(env, trip_duration, nil)
 		to.Schedule(env)
 		pc.Yield(to.Event)
 	}
 }
 
+// start starts the car process.
+func start(env *simgo.Environment) *simgo.Process {
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, car, simgo.WithName("car")))
+	p.Init()
+	return p
+}
+
 func main() {
 	env := simgo.NewEnvironment()
-	p := simgo.NewProcess(env, simgo.ProcWrapper(env, car))
-	p.Init()
+	start(env)
 	env.Run(15)
 }
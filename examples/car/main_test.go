@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/simtest"
+)
+
+func TestCar(t *testing.T) {
+	var buf bytes.Buffer
+	out = &buf
+	simtest.AssertGolden(t, "car", func(env *simgo.Environment, rng *random.Stream) {
+		start(env)
+		env.Run(15)
+	}, 0)
+	want := `Start parking at 0
+Start driving at 5
+Start parking at 7
+Start driving at 12
+Start parking at 14
+`
+	if got := buf.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -1,11 +1,16 @@
 package main // simpy example:
 import (
 	"fmt"
+	"io"
 	"log"
+	"os"
 
 	"github.com/bgmerrell/simgo"
 )
 
+// out is where the example prints its output.  Tests replace it.
+var out io.Writer = os.Stdout
+
 /*
 import simpy
 
@@ -45,7 +50,7 @@ func exampleConditions(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 		log.Fatalf("len(r) = %d, want: %d\n", len(r), 1)
 	}
 	val, err := r[0].(*simgo.EventValue).Get()
-	fmt.Printf("AnyOf() val #1: %#v\n", val)
+	fmt.Fprintf(out, "AnyOf() val #1: %#v\n", val)
 	if err != nil {
 		log.Fatalf("err = %s, want: nil", err)
 	}
@@ -66,7 +71,7 @@ func exampleConditions(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 		log.Fatalf("len(r) = %d, want: %d\n", len(r), 2)
 	}
 	val, err = r[0].(*simgo.EventValue).Get()
-	fmt.Printf("AllOf() val #1: %#v\n", val)
+	fmt.Fprintf(out, "AllOf() val #1: %#v\n", val)
 	if err != nil {
 		log.Fatalf("err = %s, want: nil", err)
 	}
@@ -74,7 +79,7 @@ func exampleConditions(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 		log.Fatalf("val = %s, want: spam", val)
 	}
 	val, err = r[1].(*simgo.EventValue).Get()
-	fmt.Printf("AllOf() val #2: %#v\n", val)
+	fmt.Fprintf(out, "AllOf() val #2: %#v\n", val)
 	if err != nil {
 		log.Fatalf("err = %s, want: nil", err)
 	}
@@ -85,12 +90,18 @@ func exampleConditions(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 	return nil
 }
 
+// start starts the example's process.
+func start(env *simgo.Environment) *simgo.Process {
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, exampleConditions, simgo.WithName("example_conditions")))
+	p.Init()
+	return p
+}
+
 func main() {
 	env := simgo.NewEnvironment()
-	p := simgo.NewProcess(env, simgo.ProcWrapper(env, exampleConditions))
-	p.Init()
+	start(env)
 	_, err := env.Run(nil)
 	if err != nil {
-		fmt.Println("Error: ", err)
+		fmt.Fprintln(out, "Error: ", err)
 	}
 }
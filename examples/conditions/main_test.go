@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/simtest"
+)
+
+func TestConditions(t *testing.T) {
+	var buf bytes.Buffer
+	out = &buf
+	simtest.AssertGolden(t, "conditions", func(env *simgo.Environment, rng *random.Stream) {
+		p := start(env)
+		simtest.ExpectProcessFinishedBy(t, env, p, 3)
+		env.Run(nil)
+	}, 0)
+	want := `AnyOf() val #1: "spam"
+AllOf() val #1: "spam"
+AllOf() val #2: "eggs"
+`
+	if got := buf.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
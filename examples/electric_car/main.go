@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/bgmerrell/simgo"
 )
 
+// out is where the example prints its output.  Tests replace it.
+var out io.Writer = os.Stdout
+
 // simpy example:
 /*
 class Car(object):
@@ -59,20 +64,20 @@ type Car struct {
 func NewCar(env *simgo.Environment) *Car {
 	c := &Car{}
 	c.env = env
-	c.action = simgo.NewProcess(env, simgo.ProcWrapper(env, c.run))
+	c.action = simgo.NewProcess(env, simgo.ProcWrapper(env, c.run, simgo.WithName("run")))
 	c.action.Init()
 	return c
 }
 
 func (c *Car) run(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 	for {
-		fmt.Printf("Start parking and charging at %d\n", env.Now)
+		fmt.Fprintf(out, "Start parking and charging at %d\n", env.Now)
 
-		chargeProc := simgo.NewProcess(env, simgo.ProcWrapper(env, c.charge))
+		chargeProc := simgo.NewProcess(env, simgo.ProcWrapper(env, c.charge, simgo.WithName("charge")))
 		chargeProc.Init()
 		pc.Yield(chargeProc.Event)
 
-		fmt.Printf("Start driving at %d\n", env.Now)
+		fmt.Fprintf(out, "Start driving at %d\n", env.Now)
 		to := simgo.NewTimeout(env, trip_duration, nil)
 		to.Schedule(env)
 		pc.Yield(to.Event)
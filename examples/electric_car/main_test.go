@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/simtest"
+)
+
+func TestElectricCar(t *testing.T) {
+	var buf bytes.Buffer
+	out = &buf
+	simtest.AssertGolden(t, "electric_car", func(env *simgo.Environment, rng *random.Stream) {
+		car := NewCar(env)
+		// The car is driving between 5 and 7.
+		simtest.AssertAt(t, env, 6, func() bool {
+			return bytes.HasSuffix(buf.Bytes(), []byte("Start driving at 5\n"))
+		})
+		car.env.Run(15)
+	}, 0)
+	want := `Start parking and charging at 0
+Start driving at 5
+Start parking and charging at 7
+Start driving at 12
+Start parking and charging at 14
+`
+	if got := buf.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/bgmerrell/simgo"
 )
 
+// out is where the example prints its output.  Tests replace it.
+var out io.Writer = os.Stdout
+
 // simpy example:
 /*
 import simpy
@@ -25,6 +30,11 @@ p = simpy.events.Process(env, gen)
 env.run(p)
 */
 
+// Output:
+/*
+return value: 42
+*/
+
 func myProc(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 	to := simgo.NewTimeout(env, 1, nil)
 	to.Schedule(env)
@@ -33,16 +43,22 @@ func myProc(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 }
 
 func otherProc(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
-	p := simgo.NewProcess(env, simgo.ProcWrapper(env, myProc))
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, myProc, simgo.WithName("my_proc")))
 	p.Init()
 	pc.Yield(p.Event)
-	fmt.Println("return value:", p.ReturnValue())
+	fmt.Fprintln(out, "return value:", p.ReturnValue())
 	return nil
 }
 
+// start starts the other_proc process.
+func start(env *simgo.Environment) *simgo.Process {
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, otherProc, simgo.WithName("other_proc")))
+	p.Init()
+	return p
+}
+
 func main() {
 	env := simgo.NewEnvironment()
-	p := simgo.NewProcess(env, simgo.ProcWrapper(env, otherProc))
-	p.Init()
+	start(env)
 	env.Run(nil)
 }
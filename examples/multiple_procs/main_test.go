@@ -0,0 +1,24 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/simtest"
+)
+
+func TestMultipleProcs(t *testing.T) {
+	var buf bytes.Buffer
+	out = &buf
+	simtest.AssertGolden(t, "multiple_procs", func(env *simgo.Environment, rng *random.Stream) {
+		p := start(env)
+		simtest.ExpectProcessFinishedBy(t, env, p, 1)
+		env.Run(nil)
+	}, 0)
+	want := "return value: 42\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
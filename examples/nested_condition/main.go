@@ -1,11 +1,16 @@
 package main // simpy example:
 import (
 	"fmt"
+	"io"
 	"log"
+	"os"
 
 	"github.com/bgmerrell/simgo"
 )
 
+// out is where the example prints its output.  Tests replace it.
+var out io.Writer = os.Stdout
+
 // Python example
 /*
 import simpy
@@ -69,7 +74,7 @@ func nestedCondition(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 		log.Fatalf("len(r) = %d, want: %d\n", len(r), 2)
 	}
 	val, err := r[0].(*simgo.EventValue).Get()
-	fmt.Printf("val #1: %#v\n", val)
+	fmt.Fprintf(out, "val #1: %#v\n", val)
 	if err != nil {
 		log.Fatalf("err = %s, want: nil", err)
 	}
@@ -77,7 +82,7 @@ func nestedCondition(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 		log.Fatalf("val = %s, want: spam", val)
 	}
 	val, err = r[1].(*simgo.EventValue).Get()
-	fmt.Printf("val #2: %#v\n", val)
+	fmt.Fprintf(out, "val #2: %#v\n", val)
 	if err != nil {
 		log.Fatalf("err = %s, want: nil", err)
 	}
@@ -86,7 +91,7 @@ func nestedCondition(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 	}
 
 	// Example nested `and` condition
-	fmt.Println("---")
+	fmt.Fprintln(out, "---")
 	t1 = simgo.NewTimeout(env, 1, "cat")
 	t2 = simgo.NewTimeout(env, 9999, "walrus")
 	t3 = simgo.NewTimeout(env, 3, "dog")
@@ -103,7 +108,7 @@ func nestedCondition(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 		log.Fatalf("len(r) = %d, want: %d\n", len(r), 2)
 	}
 	val, err = r[0].(*simgo.EventValue).Get()
-	fmt.Printf("val #1: %#v\n", val)
+	fmt.Fprintf(out, "val #1: %#v\n", val)
 	if err != nil {
 		log.Fatalf("err = %s, want: nil", err)
 	}
@@ -111,7 +116,7 @@ func nestedCondition(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 		log.Fatalf("val = %s, want: cat", val)
 	}
 	val, err = r[1].(*simgo.EventValue).Get()
-	fmt.Printf("val #2: %#v\n", val)
+	fmt.Fprintf(out, "val #2: %#v\n", val)
 	if err != nil {
 		log.Fatalf("err = %s, want: nil", err)
 	}
@@ -122,12 +127,18 @@ func nestedCondition(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 	return nil
 }
 
+// start starts the example's process.
+func start(env *simgo.Environment) *simgo.Process {
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, nestedCondition, simgo.WithName("nested_condition")))
+	p.Init()
+	return p
+}
+
 func main() {
 	env := simgo.NewEnvironment()
-	p := simgo.NewProcess(env, simgo.ProcWrapper(env, nestedCondition))
-	p.Init()
+	start(env)
 	_, err := env.Run(nil)
 	if err != nil {
-		fmt.Println("Error: ", err)
+		fmt.Fprintln(out, "Error: ", err)
 	}
 }
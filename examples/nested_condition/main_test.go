@@ -0,0 +1,29 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/simtest"
+)
+
+func TestNestedCondition(t *testing.T) {
+	var buf bytes.Buffer
+	out = &buf
+	simtest.AssertGolden(t, "nested_condition", func(env *simgo.Environment, rng *random.Stream) {
+		p := start(env)
+		simtest.ExpectProcessFinishedBy(t, env, p, 5)
+		env.Run(nil)
+	}, 0)
+	want := `val #1: "spam"
+val #2: "eggs"
+---
+val #1: "cat"
+val #2: "dog"
+`
+	if got := buf.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
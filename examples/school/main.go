@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/bgmerrell/simgo"
 )
 
+// out is where the example prints its output.  Tests replace it.
+var out io.Writer = os.Stdout
+
 // simpy example:
 /*
 import simpy
@@ -53,15 +58,15 @@ func (s *School) bell(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 		to.Schedule(env)
 		pc.Yield(to.Event)
 		s.classEnds.Succeed(nil)
-		s.classEnds = simgo.NewEvent(env)
-		fmt.Println("")
+		s.classEnds = simgo.NewEvent(env, simgo.WithName("class_ends"))
+		fmt.Fprintln(out)
 	}
 	return nil
 }
 
 func (s *School) pupil(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 	for i := 0; i < 2; i++ {
-		fmt.Printf(` \o/`)
+		fmt.Fprintf(out, ` \o/`)
 		pc.Yield(s.classEnds)
 	}
 	return nil
@@ -70,13 +75,13 @@ func (s *School) pupil(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 func NewSchool(env *simgo.Environment) *School {
 	s := &School{
 		env:       env,
-		classEnds: simgo.NewEvent(env),
+		classEnds: simgo.NewEvent(env, simgo.WithName("class_ends")),
 	}
-	s.bellProc = simgo.NewProcess(env, simgo.ProcWrapper(env, s.bell))
+	s.bellProc = simgo.NewProcess(env, simgo.ProcWrapper(env, s.bell, simgo.WithName("bell")))
 	s.bellProc.Init()
 	s.pupilProcs = make([]*simgo.Process, 0, 3)
 	for i := 0; i < 3; i++ {
-		pupilProc := simgo.NewProcess(env, simgo.ProcWrapper(env, s.pupil))
+		pupilProc := simgo.NewProcess(env, simgo.ProcWrapper(env, s.pupil, simgo.WithName(fmt.Sprintf("pupil-%d", i))))
 		pupilProc.Init()
 		s.pupilProcs = append(s.pupilProcs, pupilProc)
 	}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/simtest"
+)
+
+func TestSchool(t *testing.T) {
+	var buf bytes.Buffer
+	out = &buf
+	simtest.AssertGolden(t, "school", func(env *simgo.Environment, rng *random.Stream) {
+		school := NewSchool(env)
+		// The first class ends at 45.
+		simtest.AssertAt(t, env, 45, func() bool {
+			return buf.String() == ` \o/ \o/ \o/`+"\n"+` \o/ \o/ \o/`
+		})
+		for _, p := range school.pupilProcs {
+			simtest.ExpectProcessFinishedBy(t, env, p, 90)
+		}
+		school.env.Run(nil)
+	}, 0)
+	want := ` \o/ \o/ \o/
+ \o/ \o/ \o/
+`
+	if got := buf.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
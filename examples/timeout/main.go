@@ -2,10 +2,15 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/bgmerrell/simgo"
 )
 
+// out is where the example prints its output.  Tests replace it.
+var out io.Writer = os.Stdout
+
 // simpy example:
 /*
 import simpy
@@ -35,17 +40,23 @@ func example(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
 		to := simgo.NewTimeout(env, 10, 40+i)
 		to.Schedule(env)
 		val := pc.Yield(to.Event)
-		fmt.Printf("now=%d, value=%d\n", env.Now, val)
+		fmt.Fprintf(out, "now=%d, value=%d\n", env.Now, val)
 	}
 	return nil
 }
 
+// start starts the example's process.
+func start(env *simgo.Environment) *simgo.Process {
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, example, simgo.WithName("example")))
+	p.Init()
+	return p
+}
+
 func main() {
 	env := simgo.NewEnvironment()
-	p := simgo.NewProcess(env, simgo.ProcWrapper(env, example))
-	p.Init()
+	start(env)
 	_, err := env.Run(nil)
 	if err != nil {
-		fmt.Println("Error: ", err)
+		fmt.Fprintln(out, "Error: ", err)
 	}
 }
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/simtest"
+)
+
+func TestTimeout(t *testing.T) {
+	var buf bytes.Buffer
+	out = &buf
+	simtest.AssertGolden(t, "timeout", func(env *simgo.Environment, rng *random.Stream) {
+		p := start(env)
+		simtest.ExpectProcessFinishedBy(t, env, p, 30)
+		env.Run(nil)
+	}, 0)
+	want := `now=10, value=40
+now=20, value=41
+now=30, value=42
+`
+	if got := buf.String(); got != want {
+		t.Errorf("output:\n%s\nwant:\n%s", got, want)
+	}
+}
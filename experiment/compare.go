@@ -0,0 +1,76 @@
+package experiment
+
+import (
+	"github.com/bgmerrell/simgo/stats"
+	"github.com/juju/errgo"
+)
+
+// CompareOptions configure a comparison of two model configurations.
+type CompareOptions struct {
+	// Options configure the replications of both configurations
+	Options
+	// Independent disables common random numbers by running the second
+	// configuration with a different seed.
+	Independent bool
+}
+
+// A Comparison holds the results of comparing two model configurations.
+type Comparison struct {
+	// A and B are the reports for each configuration
+	A, B *Report
+	// Differences holds a paired-difference confidence interval for the
+	// mean of A-B for each metric
+	Differences map[string]stats.Interval
+}
+
+// Compare runs replications of two model configurations and estimates the
+// difference between their metrics.
+//
+// By default both configurations use common random numbers: replication i of
+// each configuration draws from the same substream, so model components that
+// draw from the same named streams see the same random numbers.  The
+// differences are estimated from the paired replications, which removes the
+// noise the configurations have in common.
+func Compare(a, b Model, opts CompareOptions) (*Comparison, error) {
+	if opts.Replications < 1 {
+		return nil, errgo.Newf("need at least one replication, got %d", opts.Replications)
+	}
+	optsB := opts.Options
+	if opts.Independent {
+		optsB.Seed = ^opts.Seed
+	}
+	reportA, err := Run(a, opts.Options)
+	if err != nil {
+		return nil, errgo.Notef(err, "cannot run A")
+	}
+	reportB, err := Run(b, optsB)
+	if err != nil {
+		return nil, errgo.Notef(err, "cannot run B")
+	}
+
+	c := &Comparison{
+		A:           reportA,
+		B:           reportB,
+		Differences: make(map[string]stats.Interval),
+	}
+	level := opts.Options.withDefaults().Level
+	for metric := range reportA.Estimates {
+		if _, ok := reportB.Estimates[metric]; !ok {
+			continue
+		}
+		valsA, err := values(reportA.Results, metric)
+		if err != nil {
+			return nil, err
+		}
+		valsB, err := values(reportB.Results, metric)
+		if err != nil {
+			return nil, err
+		}
+		diffs := make([]float64, len(valsA))
+		for i := range diffs {
+			diffs[i] = valsA[i] - valsB[i]
+		}
+		c.Differences[metric] = stats.ConfidenceInterval(diffs, level)
+	}
+	return c, nil
+}
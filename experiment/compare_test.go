@@ -0,0 +1,67 @@
+package experiment
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+)
+
+func TestCompare(t *testing.T) {
+	// A faster server should reduce the waiting time.
+	slow := queueModel(100, 60, 500)
+	fast := queueModel(100, 50, 500)
+	opts := CompareOptions{Options: Options{Replications: 20, Seed: 3}}
+
+	crn, err := Compare(slow, fast, opts)
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	diff := crn.Differences["wait"]
+	if diff.Lower() <= 0 {
+		t.Errorf("diff = %+v, want: a positive interval", diff)
+	}
+
+	opts.Independent = true
+	indep, err := Compare(slow, fast, opts)
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if crn.Differences["wait"].HalfWidth >= indep.Differences["wait"].HalfWidth {
+		t.Errorf("CRN half-width = %g, independent half-width = %g, want: CRN smaller",
+			crn.Differences["wait"].HalfWidth, indep.Differences["wait"].HalfWidth)
+	}
+}
+
+func TestRunAntithetic(t *testing.T) {
+	model := queueModel(100, 50, 500)
+	report, err := Run(model, Options{Replications: 10, Seed: 4, Antithetic: true})
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if len(report.Results) != 10 {
+		t.Errorf("len(report.Results) = %d, want: 10", len(report.Results))
+	}
+	if report.Estimates["customers"].Mean != 500 {
+		t.Errorf("customers = %g, want: 500", report.Estimates["customers"].Mean)
+	}
+}
+
+func TestAntitheticPairs(t *testing.T) {
+	// The runs of a pair draw complementary uniforms, so each pair
+	// averages to 1/2.
+	model := func(env *simgo.Environment, rng *random.Stream) Result {
+		rng.Float64()
+		return Result{"u": rng.Float64()}
+	}
+	report, err := Run(model, Options{Replications: 5, Seed: 4, Antithetic: true})
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	for i, result := range report.Results {
+		if math.Abs(result["u"]-0.5) > 1e-9 {
+			t.Errorf("pair %d: u = %g, want: 0.5", i, result["u"])
+		}
+	}
+}
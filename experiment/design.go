@@ -0,0 +1,105 @@
+package experiment
+
+import (
+	"github.com/bgmerrell/simgo/random"
+)
+
+// A Parameter is a model parameter that is varied by a design.
+type Parameter struct {
+	Name string
+	// Min and Max bound the values of the parameter
+	Min, Max float64
+	// Levels, if set, are the values used by FullFactorial() instead of
+	// evenly spaced values between Min and Max
+	Levels []float64
+}
+
+// A Point assigns a value to each parameter of a design.
+type Point map[string]float64
+
+// FullFactorial returns a design with every combination of parameter levels.
+// Parameters without explicit Levels use the provided number of evenly spaced
+// values from Min to Max.
+func FullFactorial(params []Parameter, levels int) []Point {
+	design := []Point{{}}
+	for _, param := range params {
+		values := param.Levels
+		if len(values) == 0 {
+			values = evenlySpaced(param.Min, param.Max, levels)
+		}
+		next := make([]Point, 0, len(design)*len(values))
+		for _, point := range design {
+			for _, val := range values {
+				p := point.with(param.Name, val)
+				next = append(next, p)
+			}
+		}
+		design = next
+	}
+	return design
+}
+
+// LatinHypercube returns a Latin hypercube design with n points: the range of
+// each parameter is divided into n equal strata, and each stratum is sampled
+// exactly once.
+func LatinHypercube(params []Parameter, n int, rng *random.Stream) []Point {
+	design := make([]Point, n)
+	for i := range design {
+		design[i] = make(Point, len(params))
+	}
+	for _, param := range params {
+		s := rng.Named(param.Name)
+		strata := make([]int, n)
+		for i := range strata {
+			strata[i] = i
+		}
+		// Fisher-Yates shuffle
+		for i := n - 1; i > 0; i-- {
+			j := s.Intn(i + 1)
+			strata[i], strata[j] = strata[j], strata[i]
+		}
+		for i, stratum := range strata {
+			u := (float64(stratum) + s.Float64()) / float64(n)
+			design[i][param.Name] = param.Min + u*(param.Max-param.Min)
+		}
+	}
+	return design
+}
+
+// RandomDesign returns a design with n points drawn uniformly at random from
+// the parameter ranges.
+func RandomDesign(params []Parameter, n int, rng *random.Stream) []Point {
+	design := make([]Point, n)
+	for i := range design {
+		design[i] = make(Point, len(params))
+	}
+	for _, param := range params {
+		s := rng.Named(param.Name)
+		for i := range design {
+			design[i][param.Name] = param.Min + s.Float64()*(param.Max-param.Min)
+		}
+	}
+	return design
+}
+
+// with returns a copy of the point with a parameter set to val.
+func (p Point) with(name string, val float64) Point {
+	q := make(Point, len(p)+1)
+	for k, v := range p {
+		q[k] = v
+	}
+	q[name] = val
+	return q
+}
+
+// evenlySpaced returns n evenly spaced values from min to max.
+func evenlySpaced(min, max float64, n int) []float64 {
+	if n <= 1 {
+		return []float64{min}
+	}
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = min + float64(i)*(max-min)/float64(n-1)
+	}
+	return values
+}
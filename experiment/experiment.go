@@ -0,0 +1,233 @@
+// Package experiment runs simgo models as statistical experiments.
+//
+// A model is described by a Model factory, which builds the model in a fresh
+// Environment, runs it and returns its output metrics.  All randomness in the
+// model must come from the provided random stream so that replications are
+// independent and reproducible:
+//
+//	report, err := experiment.Run(func(env *simgo.Environment, rng *random.Stream) experiment.Result {
+//		q := NewQueueModel(env, rng)
+//		env.Run(1000)
+//		return experiment.Result{"wait": q.MeanWait()}
+//	}, experiment.Options{Replications: 30, Seed: 42})
+package experiment
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/stats"
+	"github.com/juju/errgo"
+)
+
+// DefaultLevel is the confidence level used when none is provided.
+const DefaultLevel = 0.95
+
+// A Result holds the output metrics of a single replication by name.
+type Result map[string]float64
+
+// A Model builds a model in the provided Environment, runs it and returns
+// its output metrics.  All random numbers must be drawn from rng (or streams
+// derived from it).
+type Model func(env *simgo.Environment, rng *random.Stream) Result
+
+// Options configure an experiment.
+type Options struct {
+	// Replications is the number of independent replications to run
+	Replications int
+	// Workers is the number of replications to run concurrently.  It
+	// defaults to runtime.GOMAXPROCS(0).
+	Workers int
+	// Seed is the seed of the master random stream.  Replication i uses
+	// the i-th substream of the master stream.
+	Seed uint64
+	// Level is the confidence level of the intervals.  It defaults to
+	// DefaultLevel.
+	Level float64
+	// Metrics are the metrics to estimate.  They default to all the
+	// metrics returned by the first replication.
+	Metrics []string
+	// Antithetic makes each replication an antithetic pair: the model is
+	// run with the replication's substream and with its Antithetic()
+	// stream, and the result is the average of the two runs.
+	Antithetic bool
+}
+
+// A Report holds the results of an experiment.
+type Report struct {
+	// Results holds the result of each replication, in order
+	Results []Result
+	// Estimates holds a confidence interval for the mean of each metric
+	Estimates map[string]stats.Interval
+}
+
+// Run runs independent replications of the model as configured by opts and
+// returns a report with confidence intervals for the chosen metrics.  Each
+// replication runs in its own Environment.
+func Run(model Model, opts Options) (*Report, error) {
+	if opts.Replications < 1 {
+		return nil, errgo.Newf("need at least one replication, got %d", opts.Replications)
+	}
+	opts = opts.withDefaults()
+	report := &Report{Results: replicate(model, opts, newSubstreams(opts.Seed), 0, opts.Replications)}
+	var err error
+	report.Estimates, err = estimate(report.Results, opts.metrics(report.Results), opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// withDefaults returns the options with default values filled in.
+func (opts Options) withDefaults() Options {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.GOMAXPROCS(0)
+	}
+	if opts.Level <= 0 || opts.Level >= 1 {
+		opts.Level = DefaultLevel
+	}
+	return opts
+}
+
+// metrics returns the metrics to estimate given the results.
+func (opts Options) metrics(results []Result) []string {
+	if len(opts.Metrics) > 0 || len(results) == 0 {
+		return opts.Metrics
+	}
+	metrics := make([]string, 0, len(results[0]))
+	for metric := range results[0] {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+	return metrics
+}
+
+// A job runs a single replication of a model and returns its result.
+type job func() Result
+
+// substreams hands out the numbered substreams of a seed.  Substream k+1 is
+// derived from substream k, so each costs a single jump instead of k+1 jumps
+// from the origin.
+type substreams struct {
+	seed    uint64
+	streams []*random.Stream
+}
+
+// newSubstreams returns the substreams of the provided seed.
+func newSubstreams(seed uint64) *substreams {
+	return &substreams{seed: seed}
+}
+
+// get returns a new Stream at the start of substream i, which is the same
+// as random.New(seed).Substream(i).
+func (s *substreams) get(i int) *random.Stream {
+	for len(s.streams) <= i {
+		if len(s.streams) == 0 {
+			s.streams = append(s.streams, random.New(s.seed).Substream(0))
+		} else {
+			s.streams = append(s.streams, s.streams[len(s.streams)-1].Substream(0))
+		}
+	}
+	rng := *s.streams[i]
+	return &rng
+}
+
+// replication returns the job for replication i of the model, which draws
+// from substream i.
+func replication(model Model, opts Options, subs *substreams, i int) job {
+	rng := subs.get(i)
+	// The antithetic run draws the complements of the draws of the first
+	// run, so it starts from the same state.
+	anti := rng.Antithetic()
+	return func() Result {
+		result := model(simgo.NewEnvironment(), rng)
+		if !opts.Antithetic {
+			return result
+		}
+		other := model(simgo.NewEnvironment(), anti)
+		pair := make(Result, len(result))
+		for metric, val := range result {
+			if otherVal, ok := other[metric]; ok {
+				pair[metric] = (val + otherVal) / 2
+			}
+		}
+		return pair
+	}
+}
+
+// replicate runs n replications of the model, starting at replication
+// first, and returns their results in order.
+func replicate(model Model, opts Options, subs *substreams, first, n int) []Result {
+	jobs := make([]job, n)
+	for i := range jobs {
+		jobs[i] = replication(model, opts, subs, first+i)
+	}
+	return runJobs(jobs, opts.Workers, nil)
+}
+
+// runJobs runs the jobs using the provided number of worker goroutines and
+// returns their results in order.  If done is not nil, it is called with the
+// index and result of each job as it completes, from the calling goroutine.
+func runJobs(jobs []job, workers int, done func(i int, result Result)) []Result {
+	type completed struct {
+		i      int
+		result Result
+	}
+	results := make([]Result, len(jobs))
+	idxs := make(chan int)
+	completions := make(chan completed)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range idxs {
+				completions <- completed{i, jobs[i]()}
+			}
+		}()
+	}
+	go func() {
+		for i := range jobs {
+			idxs <- i
+		}
+		close(idxs)
+		wg.Wait()
+		close(completions)
+	}()
+	for c := range completions {
+		results[c.i] = c.result
+		if done != nil {
+			done(c.i, c.result)
+		}
+	}
+	return results
+}
+
+// values returns the values of a metric in the results.
+func values(results []Result, metric string) ([]float64, error) {
+	vals := make([]float64, len(results))
+	for i, result := range results {
+		val, ok := result[metric]
+		if !ok {
+			return nil, errgo.Newf("replication %d has no %q metric", i, metric)
+		}
+		vals[i] = val
+	}
+	return vals, nil
+}
+
+// estimate returns a confidence interval for each metric.
+func estimate(results []Result, metrics []string, level float64) (map[string]stats.Interval, error) {
+	estimates := make(map[string]stats.Interval, len(metrics))
+	for _, metric := range metrics {
+		vals, err := values(results, metric)
+		if err != nil {
+			return nil, err
+		}
+		estimates[metric] = stats.ConfidenceInterval(vals, level)
+	}
+	return estimates, nil
+}
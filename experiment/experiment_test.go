@@ -0,0 +1,92 @@
+package experiment
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+)
+
+// queueModel is an M/M/1 queue that returns the mean time customers spend
+// waiting for the server.
+func queueModel(arrivalMean, serviceMean float64, customers int) Model {
+	return func(env *simgo.Environment, rng *random.Stream) Result {
+		arrivals := rng.Named("arrivals")
+		service := rng.Named("service")
+		var freeAt, totalWait uint64
+		p := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+			for i := 0; i < customers; i++ {
+				to := simgo.NewTimeout(env, arrivals.Delay(random.Exponential(arrivalMean)), nil)
+				to.Schedule(env)
+				pc.Yield(to.Event)
+				start := env.Now
+				if freeAt > start {
+					start = freeAt
+				}
+				totalWait += start - env.Now
+				freeAt = start + service.Delay(random.Exponential(serviceMean))
+			}
+			return nil
+		}))
+		p.Init()
+		env.Run(nil)
+		return Result{
+			"wait":      float64(totalWait) / float64(customers),
+			"customers": float64(customers),
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	model := queueModel(100, 50, 2000)
+	report, err := Run(model, Options{Replications: 20, Seed: 1, Workers: 4})
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if len(report.Results) != 20 {
+		t.Fatalf("len(report.Results) = %d, want: 20", len(report.Results))
+	}
+	wait := report.Estimates["wait"]
+	// The M/M/1 mean waiting time in queue is rho/(1-rho) * service mean.
+	if want := 50.0; math.Abs(wait.Mean-want) > 3*wait.HalfWidth {
+		t.Errorf("wait = %+v, want mean near: %g", wait, want)
+	}
+	if iv := report.Estimates["customers"]; iv.Mean != 2000 || iv.HalfWidth != 0 {
+		t.Errorf("customers = %+v, want: 2000 +/- 0", iv)
+	}
+
+	// Replications must not depend on the number of workers.
+	again, err := Run(model, Options{Replications: 20, Seed: 1, Workers: 1})
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	for i := range report.Results {
+		if report.Results[i]["wait"] != again.Results[i]["wait"] {
+			t.Errorf("Replication %d: wait = %g, want: %g", i, again.Results[i]["wait"], report.Results[i]["wait"])
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	model := queueModel(100, 50, 10)
+	if _, err := Run(model, Options{}); err == nil {
+		t.Errorf("err = nil, want: non-nil")
+	}
+	if _, err := Run(model, Options{Replications: 2, Metrics: []string{"missing"}}); err == nil {
+		t.Errorf("err = nil, want: non-nil")
+	}
+}
+
+func TestSubstreams(t *testing.T) {
+	subs := newSubstreams(7)
+	// Drawing from a stream that was handed out does not change the next
+	// one handed out for the same substream.
+	subs.get(3).Uint64()
+	for i := 0; i < 5; i++ {
+		got, want := subs.get(i).Uint64(), random.New(7).Substream(uint64(i)).Uint64()
+		if got != want {
+			t.Errorf("substream %d: Uint64() = %d, want: %d", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,503 @@
+package experiment
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/stats"
+	"github.com/juju/errgo"
+)
+
+const (
+	// defaultFirstStage is the number of first-stage replications per
+	// system when none is provided
+	defaultFirstStage = 10
+	// rinottGridSize is the number of points used to integrate the
+	// chi-square densities when computing Rinott's constant
+	rinottGridSize = 200
+	// rinottTailMass is the chi-square mass below the integration grid of
+	// rinottConstant()
+	rinottTailMass = 1e-12
+	// rinottMaxConstant bounds the search for Rinott's constant
+	rinottMaxConstant = 1 << 20
+)
+
+// SelectionOptions configure a ranking-and-selection procedure.
+type SelectionOptions struct {
+	// Options configure the replications.  Replications is the number of
+	// first-stage replications per system (10 by default).
+	Options
+	// Metric is the metric the systems are ranked by
+	Metric string
+	// Minimize selects the system with the smallest mean instead of the
+	// largest.
+	Minimize bool
+	// PCS is the desired probability of correct selection for Rinott()
+	// and KN() (e.g., 0.95)
+	PCS float64
+	// IndifferenceZone is the smallest difference in means worth
+	// detecting for Rinott() and KN()
+	IndifferenceZone float64
+	// MaxReplications caps the replications per system for KN(), which
+	// then selects the best mean without a guarantee.  0 means no cap.
+	MaxReplications int
+	// Budget is the total number of replications for OCBA()
+	Budget int
+	// Increment is the number of replications OCBA() allocates per round.
+	// It defaults to the number of workers.
+	Increment int
+}
+
+// A Selection is the result of a ranking-and-selection procedure.
+type Selection struct {
+	// Best is the index of the selected system
+	Best int
+	// Means and Replications hold the sample mean of the metric and the
+	// number of replications for each system
+	Means        []float64
+	Replications []int
+	// PCS is the probability of correct selection: the guaranteed lower
+	// bound for Rinott() and KN(), and an estimate for OCBA()
+	PCS float64
+}
+
+// Rinott selects the best system using Rinott's two-stage procedure: after
+// the first stage, each system gets enough replications for its variance so
+// that the best system is selected with probability PCS whenever it is
+// better than the others by at least the indifference zone.  Systems are
+// simulated independently.
+func Rinott(systems []Model, opts SelectionOptions) (*Selection, error) {
+	cands, err := newCandidates(systems, &opts, false)
+	if err != nil {
+		return nil, err
+	}
+	if opts.IndifferenceZone <= 0 || opts.PCS <= 1.0/float64(len(systems)) || opts.PCS >= 1 {
+		return nil, errgo.New("a positive indifference zone and a PCS in (1/k, 1) are required")
+	}
+	if err := runStage(cands, uniformCounts(len(cands), opts.Replications), opts); err != nil {
+		return nil, err
+	}
+	h, err := rinottConstant(len(cands), opts.Replications, opts.PCS)
+	if err != nil {
+		return nil, err
+	}
+	extra := make([]int, len(cands))
+	for i, c := range cands {
+		n := int(math.Ceil(math.Pow(h*c.tally.StdDev()/opts.IndifferenceZone, 2)))
+		if n > opts.Replications {
+			extra[i] = n - opts.Replications
+		}
+	}
+	if err := runStage(cands, extra, opts); err != nil {
+		return nil, err
+	}
+	return newSelection(cands, opts.PCS), nil
+}
+
+// KN selects the best system using the fully sequential procedure of Kim and
+// Nelson (2001): after the first stage, one replication of every surviving
+// system is run per round and systems that are clearly worse than another
+// are eliminated, until one system remains.  The best system is selected
+// with probability PCS whenever it is better than the others by at least the
+// indifference zone.  Systems share common random numbers, which makes
+// elimination faster.
+func KN(systems []Model, opts SelectionOptions) (*Selection, error) {
+	cands, err := newCandidates(systems, &opts, true)
+	if err != nil {
+		return nil, err
+	}
+	k := len(cands)
+	if opts.IndifferenceZone <= 0 || opts.PCS <= 1.0/float64(k) || opts.PCS >= 1 {
+		return nil, errgo.New("a positive indifference zone and a PCS in (1/k, 1) are required")
+	}
+	n0 := opts.Replications
+	if err := runStage(cands, uniformCounts(k, n0), opts); err != nil {
+		return nil, err
+	}
+	pcs := opts.PCS
+	if k == 1 {
+		return newSelection(cands, pcs), nil
+	}
+
+	eta := 0.5 * (math.Pow(2*(1-opts.PCS)/float64(k-1), -2/float64(n0-1)) - 1)
+	h2 := 2 * eta * float64(n0-1)
+	delta := opts.IndifferenceZone
+	// varDiff holds the first-stage variances of pairwise differences
+	varDiff := make([][]float64, k)
+	for i := range varDiff {
+		varDiff[i] = make([]float64, k)
+		for l := range varDiff[i] {
+			diff := stats.NewTally()
+			for r := 0; r < n0; r++ {
+				diff.Add(cands[i].obs[r] - cands[l].obs[r])
+			}
+			if i != l {
+				varDiff[i][l] = diff.Variance()
+			}
+		}
+	}
+
+	surviving := make([]bool, k)
+	for i := range surviving {
+		surviving[i] = true
+	}
+	for r := n0; ; r++ {
+		eliminated := make([]bool, k)
+		for i := range cands {
+			for l := range cands {
+				if i == l || !surviving[i] || !surviving[l] {
+					continue
+				}
+				w := math.Max(0, delta/(2*float64(r))*(h2*varDiff[i][l]/(delta*delta)-float64(r)))
+				if cands[i].sum(r) < cands[l].sum(r)-float64(r)*w {
+					eliminated[i] = true
+				}
+			}
+		}
+		left := 0
+		for i := range surviving {
+			surviving[i] = surviving[i] && !eliminated[i]
+			if surviving[i] {
+				left++
+			}
+		}
+		if left <= 1 {
+			break
+		}
+		if opts.MaxReplications > 0 && r >= opts.MaxReplications {
+			// Out of budget: the guarantee no longer holds.
+			pcs = math.NaN()
+			break
+		}
+		counts := make([]int, k)
+		for i := range counts {
+			if surviving[i] {
+				counts[i] = 1
+			}
+		}
+		if err := runStage(cands, counts, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	sel := newSelection(cands, pcs)
+	// Select among the survivors only.  Eliminated systems may have a
+	// better mean over fewer replications.
+	sel.Best = -1
+	for i, c := range cands {
+		if surviving[i] && (sel.Best < 0 || c.mean() > cands[sel.Best].mean()) {
+			sel.Best = i
+		}
+	}
+	return sel, nil
+}
+
+// OCBA selects the best system using Optimal Computing Budget Allocation
+// (Chen et al., 2000): after the first stage, replications are allocated in
+// rounds, favoring systems whose means are close to the best one and whose
+// variances are large, until the budget is spent.  The probability of
+// correct selection is estimated with the approximate PCS bound.  Systems
+// are simulated independently.
+func OCBA(systems []Model, opts SelectionOptions) (*Selection, error) {
+	cands, err := newCandidates(systems, &opts, false)
+	if err != nil {
+		return nil, err
+	}
+	k := len(cands)
+	if opts.Budget < k*opts.Replications {
+		return nil, errgo.Newf("budget (%d) must cover the first stage (%d)", opts.Budget, k*opts.Replications)
+	}
+	if opts.Increment <= 0 {
+		opts.Increment = opts.Workers
+	}
+	if err := runStage(cands, uniformCounts(k, opts.Replications), opts); err != nil {
+		return nil, err
+	}
+	for spent := k * opts.Replications; spent < opts.Budget; {
+		inc := opts.Increment
+		if spent+inc > opts.Budget {
+			inc = opts.Budget - spent
+		}
+		counts := ocbaAllocation(cands, spent+inc)
+		if err := runStage(cands, counts, opts); err != nil {
+			return nil, err
+		}
+		spent += inc
+	}
+	sel := newSelection(cands, 0)
+	sel.PCS = approximatePCS(cands, sel.Best)
+	return sel, nil
+}
+
+// A candidate is a system being ranked.
+type candidate struct {
+	model  Model
+	opts   Options
+	metric string
+	// sign is -1 when minimizing, so candidates are always maximized
+	sign  float64
+	obs   []float64
+	tally *stats.Tally
+	// subs are the substreams of the candidate's seed
+	subs *substreams
+}
+
+// newCandidates validates the options, fills in defaults and returns the
+// candidates for the systems.  With common random numbers, replication r of
+// every system uses the same substream; otherwise each system uses its own
+// seed.
+func newCandidates(systems []Model, opts *SelectionOptions, crn bool) ([]*candidate, error) {
+	if len(systems) == 0 || opts.Metric == "" {
+		return nil, errgo.New("systems and a metric are required")
+	}
+	if opts.Replications < 2 {
+		opts.Replications = defaultFirstStage
+	}
+	opts.Options = opts.Options.withDefaults()
+	sign := 1.0
+	if opts.Minimize {
+		sign = -1
+	}
+	master := random.New(opts.Seed)
+	cands := make([]*candidate, len(systems))
+	for i, model := range systems {
+		c := &candidate{
+			model:  model,
+			opts:   opts.Options,
+			metric: opts.Metric,
+			sign:   sign,
+			tally:  stats.NewTally(),
+		}
+		if !crn {
+			c.opts.Seed = master.Named(fmt.Sprintf("system-%d", i)).Uint64()
+		}
+		c.subs = newSubstreams(c.opts.Seed)
+		cands[i] = c
+	}
+	return cands, nil
+}
+
+// mean returns the candidate's sample mean (negated when minimizing).
+func (c *candidate) mean() float64 {
+	return c.tally.Mean()
+}
+
+// sum returns the sum of the candidate's first r observations.
+func (c *candidate) sum(r int) float64 {
+	s := 0.0
+	for _, x := range c.obs[:r] {
+		s += x
+	}
+	return s
+}
+
+// runStage runs counts[i] more replications of each candidate i.
+func runStage(cands []*candidate, counts []int, opts SelectionOptions) error {
+	var (
+		jobs  []job
+		owner []*candidate
+	)
+	for i, c := range cands {
+		for r := len(c.obs); r < len(c.obs)+counts[i]; r++ {
+			jobs = append(jobs, replication(c.model, c.opts, c.subs, r))
+			owner = append(owner, c)
+		}
+	}
+	results := runJobs(jobs, opts.Workers, nil)
+	for i, result := range results {
+		val, ok := result[opts.Metric]
+		if !ok {
+			return errgo.Newf("replication has no %q metric", opts.Metric)
+		}
+		c := owner[i]
+		c.obs = append(c.obs, c.sign*val)
+		c.tally.Add(c.sign * val)
+	}
+	return nil
+}
+
+// newSelection returns the selection of the candidate with the best mean.
+func newSelection(cands []*candidate, pcs float64) *Selection {
+	sel := &Selection{
+		Means:        make([]float64, len(cands)),
+		Replications: make([]int, len(cands)),
+		PCS:          pcs,
+	}
+	for i, c := range cands {
+		sel.Means[i] = c.sign * c.mean()
+		sel.Replications[i] = len(c.obs)
+		if c.mean() > cands[sel.Best].mean() {
+			sel.Best = i
+		}
+	}
+	return sel
+}
+
+// uniformCounts returns n for each of k systems.
+func uniformCounts(k, n int) []int {
+	counts := make([]int, k)
+	for i := range counts {
+		counts[i] = n
+	}
+	return counts
+}
+
+// ocbaAllocation returns the number of additional replications for each
+// candidate so that the total approaches the OCBA allocation of the provided
+// total budget.
+func ocbaAllocation(cands []*candidate, total int) []int {
+	k := len(cands)
+	best := 0
+	for i, c := range cands {
+		if c.mean() > cands[best].mean() {
+			best = i
+		}
+	}
+	// Relative allocations: N_i ~ (s_i/d_i)^2 for i != best and
+	// N_best = s_best * sqrt(sum N_i^2/s_i^2).
+	ratios := make([]float64, k)
+	sumSq := 0.0
+	for i, c := range cands {
+		if i == best {
+			continue
+		}
+		d := math.Max(cands[best].mean()-c.mean(), 1e-9)
+		s := math.Max(c.tally.StdDev(), 1e-9)
+		ratios[i] = (s / d) * (s / d)
+		sumSq += ratios[i] * ratios[i] / (s * s)
+	}
+	ratios[best] = math.Max(cands[best].tally.StdDev(), 1e-9) * math.Sqrt(sumSq)
+	sum := 0.0
+	for _, r := range ratios {
+		sum += r
+	}
+
+	current := 0
+	deficits := make([]float64, k)
+	deficitSum := 0.0
+	for i, c := range cands {
+		current += len(c.obs)
+		target := float64(total) * ratios[i] / sum
+		deficits[i] = math.Max(0, target-float64(len(c.obs)))
+		deficitSum += deficits[i]
+	}
+	counts := make([]int, k)
+	inc := total - current
+	if deficitSum == 0 {
+		counts[best] = inc
+		return counts
+	}
+	// Hand out the increment in proportion to the deficits, giving the
+	// remainder to the largest deficits.
+	given := 0
+	for i := range counts {
+		counts[i] = int(float64(inc) * deficits[i] / deficitSum)
+		given += counts[i]
+	}
+	for ; given < inc; given++ {
+		largest := 0
+		for i := range deficits {
+			if deficits[i]-float64(counts[i]) > deficits[largest]-float64(counts[largest]) {
+				largest = i
+			}
+		}
+		counts[largest]++
+	}
+	return counts
+}
+
+// approximatePCS returns the Bonferroni lower bound on the probability that
+// the best candidate is correctly selected, using a normal approximation.
+func approximatePCS(cands []*candidate, best int) float64 {
+	b := cands[best]
+	pcs := 1.0
+	for i, c := range cands {
+		if i == best {
+			continue
+		}
+		se := math.Sqrt(b.tally.Variance()/float64(len(b.obs)) + c.tally.Variance()/float64(len(c.obs)))
+		if se == 0 {
+			continue
+		}
+		pcs -= normalCDF(-(b.mean() - c.mean()) / se)
+	}
+	return math.Max(pcs, 0)
+}
+
+// rinottConstant returns Rinott's constant h for k systems, n0 first-stage
+// replications and the provided PCS.  h solves
+//
+//	E[ Phi(h / sqrt((n0-1)(1/X + 1/Y)))^(k-1) ] = PCS
+//
+// where X and Y are independent chi-square variables with n0-1 degrees of
+// freedom.  Returns an error if no h up to rinottMaxConstant reaches the PCS.
+func rinottConstant(k, n0 int, pcs float64) (float64, error) {
+	if k == 1 {
+		return 0, nil
+	}
+	df := float64(n0 - 1)
+	// Integrate over x = e^t, which handles the density near zero, from
+	// where the chi-square mass below is about 1e-12.  That mass is put at
+	// the first point, and the weights are normalized so that the integrand
+	// tends to 1 as h grows, even with few degrees of freedom.
+	lg, _ := math.Lgamma(df/2 + 1)
+	x0 := 2 * math.Exp((math.Log(rinottTailMass)+lg)*2/df)
+	lo, hi := math.Log(x0), math.Log(df+40*math.Sqrt(2*df)+40)
+	dt := (hi - lo) / rinottGridSize
+	xs := make([]float64, rinottGridSize+1)
+	ws := make([]float64, rinottGridSize+1)
+	sum := 0.0
+	for j := range xs {
+		x := math.Exp(lo + float64(j)*dt)
+		xs[j] = x
+		ws[j] = chiSquarePDF(x, df) * x * dt
+		if j == 0 || j == rinottGridSize {
+			ws[j] /= 2
+		}
+		sum += ws[j]
+	}
+	ws[0] += rinottTailMass
+	sum += rinottTailMass
+	for j := range ws {
+		ws[j] /= sum
+	}
+	pcsOf := func(h float64) float64 {
+		total := 0.0
+		for i, y := range xs {
+			inner := 0.0
+			for j, x := range xs {
+				inner += normalCDF(h/math.Sqrt(df*(1/x+1/y))) * ws[j]
+			}
+			total += math.Pow(inner, float64(k-1)) * ws[i]
+		}
+		return total
+	}
+	lower, upper := 0.0, 1.0
+	for pcsOf(upper) < pcs {
+		if upper >= rinottMaxConstant {
+			return 0, errgo.Newf("cannot reach a PCS of %g with %d first-stage replications", pcs, n0)
+		}
+		lower, upper = upper, upper*2
+	}
+	for upper-lower > 1e-6*upper {
+		mid := (lower + upper) / 2
+		if pcsOf(mid) < pcs {
+			lower = mid
+		} else {
+			upper = mid
+		}
+	}
+	return (lower + upper) / 2, nil
+}
+
+// chiSquarePDF returns the chi-square density with df degrees of freedom.
+func chiSquarePDF(x, df float64) float64 {
+	lg, _ := math.Lgamma(df / 2)
+	return math.Exp((df/2-1)*math.Log(x) - x/2 - df/2*math.Ln2 - lg)
+}
+
+// normalCDF returns the standard normal CDF.
+func normalCDF(z float64) float64 {
+	return 0.5 * math.Erfc(-z/math.Sqrt2)
+}
@@ -0,0 +1,152 @@
+package experiment
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+)
+
+// normalModels returns models whose "value" metric is normally distributed
+// with the provided means.  Half of the variance is shared by all the models
+// when they use common random numbers.
+func normalModels(stdDev float64, means ...float64) []Model {
+	noise := random.Normal(0, stdDev/math.Sqrt2)
+	models := make([]Model, len(means))
+	for i, mean := range means {
+		mean, name := mean, fmt.Sprintf("system-%d", i)
+		models[i] = func(env *simgo.Environment, rng *random.Stream) Result {
+			return Result{"value": mean + noise.Sample(rng) + noise.Sample(rng.Named(name))}
+		}
+	}
+	return models
+}
+
+func TestRinottConstant(t *testing.T) {
+	// Checked by Monte Carlo.
+	if h, err := rinottConstant(2, 10, 0.95); err != nil || math.Abs(h-2.614) > 0.005 {
+		t.Errorf("h, err = %g, %v, want: 2.614, nil", h, err)
+	}
+	// h grows with the number of systems.
+	if h, err := rinottConstant(5, 10, 0.95); err != nil || h <= 2.614 {
+		t.Errorf("h, err = %g, %v, want: more than 2.614, nil", h, err)
+	}
+	// With a single degree of freedom, h is large but finite.
+	h, err := rinottConstant(10, 2, 0.995)
+	if err != nil || h <= 2.614 || math.IsInf(h, 0) {
+		t.Errorf("h, err = %g, %v, want: a finite h, nil", h, err)
+	}
+
+	// Rinott() works with two first-stage replications.
+	sel, err := Rinott(normalModels(0.2, 1, 2), SelectionOptions{
+		Options:          Options{Seed: 1, Replications: 2},
+		Metric:           "value",
+		PCS:              0.9,
+		IndifferenceZone: 0.5,
+	})
+	if err != nil || sel.Best != 1 {
+		t.Errorf("Rinott() = %+v, %v, want: system 1 selected", sel, err)
+	}
+}
+
+func TestRinott(t *testing.T) {
+	systems := normalModels(2, 1, 2, 3, 4)
+	opts := SelectionOptions{
+		Options:          Options{Seed: 1},
+		Metric:           "value",
+		PCS:              0.95,
+		IndifferenceZone: 0.5,
+	}
+	sel, err := Rinott(systems, opts)
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if sel.Best != 3 {
+		t.Errorf("sel.Best = %d, want: 3 (means: %v)", sel.Best, sel.Means)
+	}
+	if sel.PCS != 0.95 {
+		t.Errorf("sel.PCS = %g, want: 0.95", sel.PCS)
+	}
+	for i, n := range sel.Replications {
+		if n < 10 {
+			t.Errorf("sel.Replications[%d] = %d, want: at least 10", i, n)
+		}
+	}
+
+	opts.Minimize = true
+	sel, err = Rinott(systems, opts)
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if sel.Best != 0 {
+		t.Errorf("sel.Best = %d, want: 0 (means: %v)", sel.Best, sel.Means)
+	}
+}
+
+func TestKN(t *testing.T) {
+	systems := normalModels(2, 1, 2, 3, 4)
+	sel, err := KN(systems, SelectionOptions{
+		Options:          Options{Seed: 2},
+		Metric:           "value",
+		PCS:              0.95,
+		IndifferenceZone: 0.5,
+	})
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if sel.Best != 3 {
+		t.Errorf("sel.Best = %d, want: 3 (means: %v)", sel.Best, sel.Means)
+	}
+	// Clearly inferior systems should be eliminated early.
+	if sel.Replications[0] >= sel.Replications[3] {
+		t.Errorf("sel.Replications = %v, want: fewer for system 0 than system 3", sel.Replications)
+	}
+
+	sel, err = KN(normalModels(2, 1, 1), SelectionOptions{
+		Metric:           "value",
+		PCS:              0.95,
+		IndifferenceZone: 0.01,
+		MaxReplications:  50,
+	})
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if !math.IsNaN(sel.PCS) {
+		t.Errorf("sel.PCS = %g, want: NaN once out of budget", sel.PCS)
+	}
+}
+
+func TestOCBA(t *testing.T) {
+	systems := normalModels(2, 1, 2, 3, 3.5)
+	sel, err := OCBA(systems, SelectionOptions{
+		Options: Options{Seed: 3, Workers: 4},
+		Metric:  "value",
+		Budget:  400,
+	})
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if sel.Best != 3 {
+		t.Errorf("sel.Best = %d, want: 3 (means: %v)", sel.Best, sel.Means)
+	}
+	total := 0
+	for _, n := range sel.Replications {
+		total += n
+	}
+	if total != 400 {
+		t.Errorf("total replications = %d, want: 400", total)
+	}
+	// Most of the budget should go to the two best systems.
+	if sel.Replications[0] >= sel.Replications[2] {
+		t.Errorf("sel.Replications = %v, want: fewer for system 0 than system 2", sel.Replications)
+	}
+	if sel.PCS < 0.5 || sel.PCS > 1 {
+		t.Errorf("sel.PCS = %g, want: in [0.5, 1]", sel.PCS)
+	}
+
+	if _, err := OCBA(systems, SelectionOptions{Metric: "value", Budget: 10}); err == nil {
+		t.Errorf("err = nil, want: an error for a budget below the first stage")
+	}
+}
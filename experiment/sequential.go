@@ -0,0 +1,160 @@
+package experiment
+
+import (
+	"math"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/stats"
+	"github.com/juju/errgo"
+)
+
+const (
+	// defaultInitialReplications is the number of replications
+	// RunSequential() starts with when none is provided
+	defaultInitialReplications = 10
+	// defaultMaxReplications caps RunSequential() when no cap is provided
+	defaultMaxReplications = 1000
+)
+
+// SequentialOptions configure an experiment that adds replications until a
+// target precision is reached.
+type SequentialOptions struct {
+	// Options configure the replications.  Replications is the number of
+	// replications to start with (10 by default).
+	Options
+	// Metric is the metric whose precision is controlled
+	Metric string
+	// Precision is the target relative half-width of the metric's
+	// confidence interval (e.g., 0.05 for +/- 5%)
+	Precision float64
+	// MaxReplications caps the total number of replications (1000 by
+	// default)
+	MaxReplications int
+	// Step is the number of replications added per round.  It defaults
+	// to the number of workers.
+	Step int
+}
+
+// A SequentialReport holds the results of a sequential experiment.
+type SequentialReport struct {
+	*Report
+	// Precision is the relative half-width achieved for the metric
+	Precision float64
+	// Converged is whether the target precision was reached
+	Converged bool
+}
+
+// RunSequential runs replications of the model, adding more until the
+// relative half-width of the chosen metric's confidence interval falls below
+// the target precision or the replication cap is reached.  Replication i uses
+// the same random substream as in Run(), so results are reproducible.
+func RunSequential(model Model, opts SequentialOptions) (*SequentialReport, error) {
+	if opts.Metric == "" || opts.Precision <= 0 {
+		return nil, errgo.New("a metric and a positive precision are required")
+	}
+	if opts.Replications < 2 {
+		opts.Replications = defaultInitialReplications
+	}
+	if opts.MaxReplications <= 0 {
+		opts.MaxReplications = defaultMaxReplications
+	}
+	opts.Options = opts.Options.withDefaults()
+	if opts.Step <= 0 {
+		opts.Step = opts.Workers
+	}
+	var results []Result
+	subs := newSubstreams(opts.Seed)
+	n := opts.Replications
+	for {
+		if n > opts.MaxReplications {
+			n = opts.MaxReplications
+		}
+		results = append(results, replicate(model, opts.Options, subs, len(results), n-len(results))...)
+
+		vals, err := values(results, opts.Metric)
+		if err != nil {
+			return nil, err
+		}
+		iv := stats.ConfidenceInterval(vals, opts.Level)
+		precision := iv.RelativeHalfWidth()
+		converged := precision <= opts.Precision
+		if converged || len(results) >= opts.MaxReplications {
+			report := &SequentialReport{
+				Report:    &Report{Results: results},
+				Precision: precision,
+				Converged: converged,
+			}
+			metrics := opts.metrics(results)
+			report.Estimates, err = estimate(results, metrics, opts.Level)
+			if err != nil {
+				return nil, err
+			}
+			report.Estimates[opts.Metric] = iv
+			return report, nil
+		}
+		n = len(results) + opts.Step
+	}
+}
+
+// HorizonOptions configure ExtendRun().
+type HorizonOptions struct {
+	// Step is how far the horizon is extended per round
+	Step uint64
+	// MaxTime caps the simulation time.  It is required.
+	MaxTime uint64
+	// Precision is the target relative half-width
+	Precision float64
+	// Level is the confidence level.  It defaults to DefaultLevel.
+	Level float64
+}
+
+// A HorizonReport holds the results of ExtendRun().
+type HorizonReport struct {
+	// Estimate is the steady-state confidence interval
+	Estimate stats.Interval
+	// Warmup is the number of observations discarded as warm-up
+	Warmup int
+	// Precision is the relative half-width achieved
+	Precision float64
+	// Converged is whether the target precision was reached
+	Converged bool
+}
+
+// ExtendRun runs a single long simulation in steps, extending the Run()
+// horizon until the steady-state estimate of the observations returned by
+// observe reaches the target precision or the time cap is reached.  The
+// estimate is computed with stats.SteadyState() after each step.
+func ExtendRun(env *simgo.Environment, observe func() []float64, opts HorizonOptions) (*HorizonReport, error) {
+	if opts.Step == 0 || opts.Precision <= 0 {
+		return nil, errgo.New("a positive step and precision are required")
+	}
+	if opts.MaxTime == 0 {
+		return nil, errgo.New("MaxTime is required")
+	}
+	if opts.Level <= 0 || opts.Level >= 1 {
+		opts.Level = DefaultLevel
+	}
+	report := &HorizonReport{Precision: math.Inf(1)}
+	for env.Now < opts.MaxTime {
+		horizon := env.Now + opts.Step
+		if horizon > opts.MaxTime {
+			horizon = opts.MaxTime
+		}
+		if _, err := env.Run(horizon); err != nil {
+			return nil, err
+		}
+		iv, warmup, err := stats.SteadyState(observe(), opts.Level)
+		if err == nil {
+			report.Estimate, report.Warmup = iv, warmup
+			report.Precision = iv.RelativeHalfWidth()
+			if report.Precision <= opts.Precision {
+				report.Converged = true
+				return report, nil
+			}
+		}
+	}
+	if report.Estimate.N == 0 {
+		return nil, errgo.Newf("too few observations for a steady-state estimate by time %d", env.Now)
+	}
+	return report, nil
+}
@@ -0,0 +1,90 @@
+package experiment
+
+import (
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/stats"
+)
+
+func TestRunSequential(t *testing.T) {
+	model := queueModel(100, 50, 200)
+	report, err := RunSequential(model, SequentialOptions{
+		Options:   Options{Seed: 1},
+		Metric:    "wait",
+		Precision: 0.1,
+	})
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if !report.Converged || report.Precision > 0.1 {
+		t.Errorf("report.Converged, report.Precision = %t, %g, want: true, <= 0.1", report.Converged, report.Precision)
+	}
+
+	// Capped runs report the precision they achieved.
+	capped, err := RunSequential(model, SequentialOptions{
+		Options:         Options{Seed: 1},
+		Metric:          "wait",
+		Precision:       0.001,
+		MaxReplications: 12,
+	})
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if capped.Converged || len(capped.Results) != 12 {
+		t.Errorf("capped.Converged, len(capped.Results) = %t, %d, want: false, 12", capped.Converged, len(capped.Results))
+	}
+	for i := range capped.Results {
+		if capped.Results[i]["wait"] != report.Results[i]["wait"] {
+			t.Errorf("Replication %d differs between runs", i)
+		}
+	}
+}
+
+func TestExtendRun(t *testing.T) {
+	env := simgo.NewEnvironment()
+	rng := random.New(5)
+	arrivals, service := rng.Named("arrivals"), rng.Named("service")
+	waits := stats.NewTally()
+	waits.Record()
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		var freeAt uint64
+		for {
+			to := simgo.NewTimeout(env, arrivals.Delay(random.Exponential(100)), nil)
+			to.Schedule(env)
+			pc.Yield(to.Event)
+			start := env.Now
+			if freeAt > start {
+				start = freeAt
+			}
+			waits.Add(float64(start - env.Now))
+			freeAt = start + service.Delay(random.Exponential(50))
+		}
+	}))
+	p.Init()
+
+	report, err := ExtendRun(env, waits.Observations, HorizonOptions{
+		Step:      100000,
+		MaxTime:   100000000,
+		Precision: 0.1,
+	})
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if !report.Converged {
+		t.Errorf("report = %+v, want: converged", report)
+	}
+	if env.Now%100000 != 0 {
+		t.Errorf("env.Now = %d, want: a multiple of the step", env.Now)
+	}
+}
+
+func TestExtendRunMaxTime(t *testing.T) {
+	env := simgo.NewEnvironment()
+	observe := func() []float64 { return nil }
+	_, err := ExtendRun(env, observe, HorizonOptions{Step: 100, Precision: 0.1})
+	if err == nil || err.Error() != "MaxTime is required" {
+		t.Errorf("err = %v, want: MaxTime is required", err)
+	}
+}
@@ -0,0 +1,294 @@
+package experiment
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/juju/errgo"
+)
+
+// SweepOptions configure a parameter sweep.
+type SweepOptions struct {
+	// Options configure the replications run at each design point.
+	// Replication r of every point uses the same random substream, so
+	// points are compared with common random numbers.
+	Options
+	// Journal, if set, is the path of a JSON Lines file that every
+	// completed run is appended to.  Runs already in the journal are not
+	// run again, so an interrupted sweep can be resumed.
+	Journal string
+}
+
+// A Row is the result of one replication at one design point.
+type Row struct {
+	// Point is the index of the design point
+	Point int `json:"point"`
+	// Replication is the index of the replication
+	Replication int    `json:"replication"`
+	Params      Point  `json:"params"`
+	Result      Result `json:"result"`
+}
+
+// A Table holds the results of a sweep.
+type Table struct {
+	// Params and Metrics are the sorted names of the parameters and
+	// metrics in the table
+	Params  []string
+	Metrics []string
+	// Rows are sorted by point and replication
+	Rows []Row
+}
+
+// Sweep runs the configured number of replications of the model at each
+// point of the design.  The factory returns the model for a design point.
+// Runs are spread across the configured number of workers.
+func Sweep(design []Point, factory func(Point) Model, opts SweepOptions) (*Table, error) {
+	if opts.Replications < 1 {
+		return nil, errgo.Newf("need at least one replication, got %d", opts.Replications)
+	}
+	opts.Options = opts.Options.withDefaults()
+
+	var rows []Row
+	done := make(map[[2]int]bool)
+	var journal *json.Encoder
+	if opts.Journal != "" {
+		var err error
+		rows, err = readJournal(opts.Journal, design, opts.Replications)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			done[[2]int{row.Point, row.Replication}] = true
+		}
+		f, err := os.OpenFile(opts.Journal, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, errgo.Notef(err, "cannot open journal")
+		}
+		defer f.Close()
+		journal = json.NewEncoder(f)
+	}
+
+	var (
+		jobs    []job
+		pending []Row
+	)
+	subs := newSubstreams(opts.Seed)
+	for p, point := range design {
+		model := factory(point)
+		for r := 0; r < opts.Replications; r++ {
+			if done[[2]int{p, r}] {
+				continue
+			}
+			jobs = append(jobs, replication(model, opts.Options, subs, r))
+			pending = append(pending, Row{Point: p, Replication: r, Params: point})
+		}
+	}
+	var journalErr error
+	runJobs(jobs, opts.Workers, func(i int, result Result) {
+		pending[i].Result = result
+		if journal != nil && journalErr == nil {
+			journalErr = journal.Encode(pending[i])
+		}
+	})
+	if journalErr != nil {
+		return nil, errgo.Notef(journalErr, "cannot write journal")
+	}
+	return newTable(append(rows, pending...)), nil
+}
+
+// WriteCSV writes the table as CSV with a header row.  Each row holds the
+// point and replication indexes followed by the parameters and metrics.
+func (t *Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := append([]string{"point", "replication"}, t.Params...)
+	cw.Write(append(header, t.Metrics...))
+	for _, row := range t.Rows {
+		record := []string{strconv.Itoa(row.Point), strconv.Itoa(row.Replication)}
+		for _, name := range t.Params {
+			record = append(record, formatFloat(row.Params, name))
+		}
+		for _, name := range t.Metrics {
+			record = append(record, formatFloat(row.Result, name))
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the rows of the table as a JSON array.  Values that are
+// not finite are written as strings (see Result.MarshalJSON()).
+func (t *Table) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(t.Rows)
+}
+
+// newTable returns a table with the provided rows.
+func newTable(rows []Row) *Table {
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Point != rows[j].Point {
+			return rows[i].Point < rows[j].Point
+		}
+		return rows[i].Replication < rows[j].Replication
+	})
+	params := make(map[string]bool)
+	metrics := make(map[string]bool)
+	for _, row := range rows {
+		for name := range row.Params {
+			params[name] = true
+		}
+		for name := range row.Result {
+			metrics[name] = true
+		}
+	}
+	return &Table{
+		Params:  sortedKeys(params),
+		Metrics: sortedKeys(metrics),
+		Rows:    rows,
+	}
+}
+
+// readJournal reads the rows in a sweep journal and checks that they match
+// the design.  Duplicate rows, and rows of replications beyond the configured
+// number, are dropped.  A missing journal has no rows.
+//
+// A run interrupted while writing leaves a partial last line, which is
+// truncated from the journal (and run again), so that the next row is not
+// appended to it.
+func readJournal(path string, design []Point, replications int) ([]Row, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errgo.Notef(err, "cannot read journal")
+	}
+	if complete := bytes.LastIndexByte(data, '\n') + 1; complete < len(data) {
+		if err := os.Truncate(path, int64(complete)); err != nil {
+			return nil, errgo.Notef(err, "cannot truncate journal")
+		}
+		data = data[:complete]
+	}
+
+	var rows []Row
+	seen := make(map[[2]int]bool)
+	for i, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		var row Row
+		if err := json.Unmarshal(line, &row); err != nil {
+			return nil, errgo.Notef(err, "invalid journal line %d", i+1)
+		}
+		if row.Point < 0 || row.Point >= len(design) || row.Replication < 0 || !equalPoints(row.Params, design[row.Point]) {
+			return nil, errgo.Newf("journal line %d does not match the design", i+1)
+		}
+		key := [2]int{row.Point, row.Replication}
+		if row.Replication >= replications || seen[key] {
+			continue
+		}
+		seen[key] = true
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// equalPoints returns whether two points have the same parameter values.
+func equalPoints(a, b Point) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, val := range a {
+		if other, ok := b[name]; !ok || other != val {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalJSON encodes the result as a JSON object, with NaN and infinite
+// values as the strings "NaN", "+Inf" and "-Inf", which JSON numbers cannot
+// represent.
+func (r Result) MarshalJSON() ([]byte, error) {
+	return marshalValues(r)
+}
+
+// UnmarshalJSON decodes a result encoded by MarshalJSON().
+func (r *Result) UnmarshalJSON(data []byte) error {
+	return unmarshalValues(data, (*map[string]float64)(r))
+}
+
+// MarshalJSON encodes the point like Result.MarshalJSON().
+func (p Point) MarshalJSON() ([]byte, error) {
+	return marshalValues(p)
+}
+
+// UnmarshalJSON decodes a point encoded by MarshalJSON().
+func (p *Point) UnmarshalJSON(data []byte) error {
+	return unmarshalValues(data, (*map[string]float64)(p))
+}
+
+// marshalValues encodes named values as a JSON object, with the values that
+// are not finite as strings.
+func marshalValues(values map[string]float64) ([]byte, error) {
+	obj := make(map[string]interface{}, len(values))
+	for name, val := range values {
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			obj[name] = strconv.FormatFloat(val, 'g', -1, 64)
+		} else {
+			obj[name] = val
+		}
+	}
+	return json.Marshal(obj)
+}
+
+// unmarshalValues decodes named values encoded by marshalValues().
+func unmarshalValues(data []byte, values *map[string]float64) error {
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	if obj == nil {
+		*values = nil
+		return nil
+	}
+	*values = make(map[string]float64, len(obj))
+	for name, raw := range obj {
+		var val float64
+		var str string
+		if err := json.Unmarshal(raw, &str); err == nil {
+			if val, err = strconv.ParseFloat(str, 64); err != nil {
+				return errgo.Notef(err, "invalid value of %q", name)
+			}
+		} else if err := json.Unmarshal(raw, &val); err != nil {
+			return errgo.Notef(err, "invalid value of %q", name)
+		}
+		(*values)[name] = val
+	}
+	return nil
+}
+
+// formatFloat formats a named value, or returns an empty string if there is
+// no such value.
+func formatFloat(values map[string]float64, name string) string {
+	val, ok := values[name]
+	if !ok {
+		return ""
+	}
+	return strconv.FormatFloat(val, 'g', -1, 64)
+}
+
+// sortedKeys returns the keys of a set in sorted order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
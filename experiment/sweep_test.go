@@ -0,0 +1,139 @@
+package experiment
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/stats"
+)
+
+func TestFullFactorial(t *testing.T) {
+	design := FullFactorial([]Parameter{
+		{Name: "servers", Levels: []float64{1, 2}},
+		{Name: "rate", Min: 0, Max: 1},
+	}, 3)
+	if len(design) != 6 {
+		t.Fatalf("len(design) = %d, want: 6", len(design))
+	}
+	if design[5]["servers"] != 2 || design[5]["rate"] != 1 || design[1]["rate"] != 0.5 {
+		t.Errorf("design = %v, want servers in {1, 2} and rate in {0, 0.5, 1}", design)
+	}
+}
+
+func TestLatinHypercube(t *testing.T) {
+	const n = 10
+	design := LatinHypercube([]Parameter{{Name: "x", Min: 0, Max: 10}}, n, random.New(1))
+	strata := make(map[int]bool)
+	for _, point := range design {
+		strata[int(math.Floor(point["x"]))] = true
+	}
+	if len(strata) != n {
+		t.Errorf("strata = %v, want: one point in each of %d strata", strata, n)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	journal := filepath.Join(t.TempDir(), "sweep.jsonl")
+	design := FullFactorial([]Parameter{{Name: "service", Levels: []float64{40, 60}}}, 0)
+	runs := 0
+	factory := func(p Point) Model {
+		model := queueModel(100, p["service"], 100)
+		return func(env *simgo.Environment, rng *random.Stream) Result {
+			runs++
+			return model(env, rng)
+		}
+	}
+	opts := SweepOptions{Options: Options{Replications: 3, Seed: 1, Workers: 1}, Journal: journal}
+	table, err := Sweep(design, factory, opts)
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if len(table.Rows) != 6 || runs != 6 {
+		t.Fatalf("len(table.Rows), runs = %d, %d, want: 6, 6", len(table.Rows), runs)
+	}
+
+	// Simulate an interrupted sweep by dropping the last two runs from the
+	// journal, leaving a partial line, and add a duplicate run and a run of
+	// a replication beyond those configured.
+	data, err := os.ReadFile(journal)
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	extra := strings.Replace(lines[0], `"replication":0`, `"replication":7`, 1)
+	interrupted := strings.Join(lines[:4], "") + lines[0] + extra + lines[4][:10]
+	if err := os.WriteFile(journal, []byte(interrupted), 0644); err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	var want bytes.Buffer
+	table.WriteCSV(&want)
+	// The second resume has nothing left to run.
+	for i, wantRuns := range []int{2, 0} {
+		runs = 0
+		resumed, err := Sweep(design, factory, opts)
+		if err != nil {
+			t.Fatalf("resume %d: err = %s, want: nil", i, err)
+		}
+		if runs != wantRuns {
+			t.Errorf("resume %d: runs = %d, want: %d", i, runs, wantRuns)
+		}
+		var got bytes.Buffer
+		resumed.WriteCSV(&got)
+		if got.String() != want.String() {
+			t.Errorf("resume %d: table:\n%s\nwant:\n%s", i, got.String(), want.String())
+		}
+	}
+	if !strings.HasPrefix(want.String(), "point,replication,service,customers,wait\n0,0,40,100,") {
+		t.Errorf("table:\n%s\nwant a header and rows", want.String())
+	}
+
+	// A journal that does not match the design is rejected.
+	bad := strings.Replace(lines[0], `"point":0`, `"point":-1`, 1)
+	if err := os.WriteFile(journal, []byte(bad), 0644); err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if _, err := Sweep(design, factory, opts); err == nil {
+		t.Errorf("err = nil, want: an error for a point not in the design")
+	}
+}
+
+func TestSweepNonFinite(t *testing.T) {
+	journal := filepath.Join(t.TempDir(), "sweep.jsonl")
+	design := FullFactorial([]Parameter{{Name: "x", Levels: []float64{0, 1}}}, 0)
+	runs := 0
+	factory := func(p Point) Model {
+		return func(env *simgo.Environment, rng *random.Stream) Result {
+			runs++
+			// The mean of no observations is NaN.
+			return Result{"mean": stats.NewTally().Mean(), "ratio": 1 / p["x"]}
+		}
+	}
+	opts := SweepOptions{Options: Options{Replications: 2, Seed: 1, Workers: 1}, Journal: journal}
+	if _, err := Sweep(design, factory, opts); err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	runs = 0
+	table, err := Sweep(design, factory, opts)
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if runs != 0 || len(table.Rows) != 4 {
+		t.Fatalf("runs, len(table.Rows) = %d, %d, want: 0, 4 from the journal", runs, len(table.Rows))
+	}
+	if row := table.Rows[0]; !math.IsNaN(row.Result["mean"]) || !math.IsInf(row.Result["ratio"], 1) {
+		t.Errorf("row.Result = %v, want: NaN mean and +Inf ratio", row.Result)
+	}
+	var buf bytes.Buffer
+	if err := table.WriteJSON(&buf); err != nil {
+		t.Errorf("WriteJSON() = %v, want: nil", err)
+	}
+	if !strings.Contains(buf.String(), `"mean":"NaN"`) {
+		t.Errorf("WriteJSON() wrote %s, want: NaN as a string", buf.String())
+	}
+}
@@ -0,0 +1,452 @@
+// Package fault injects faults into simgo simulations: processes crash, are
+// restarted or paused, and resources are degraded, at scripted or randomly
+// generated simulation times.
+//
+// Targets are registered with an Injector by name: processes are started
+// through it so that they can be restarted, and resources implement
+// Degradable.  Faults are then scheduled from a script, or generated from a
+// seed:
+//
+//	in := fault.NewInjector(env)
+//	in.Go("server", serve)
+//	in.Resource("disk", disk)
+//	in.Schedule(
+//		fault.Fault{Time: 100, Kind: fault.Crash, Target: "server"},
+//		fault.Fault{Time: 150, Kind: fault.Restart, Target: "server"},
+//	)
+//
+// Every fault is injected by a Timeout named "Fault" whose value is the
+// Fault, so it appears in traces (see the trace package), and FromTrace()
+// turns the faults of a trace back into a script to replay a failing
+// scenario.
+package fault
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/trace"
+	"github.com/juju/errgo"
+)
+
+// EventName is the name of the events that inject faults.
+const EventName = "Fault"
+
+// A Kind is a kind of fault.
+type Kind string
+
+const (
+	// Crash kills a process (see simgo.Process.Kill())
+	Crash Kind = "crash"
+	// Restart kills a process if it is running, and starts its function
+	// again in a new process
+	Restart Kind = "restart"
+	// Pause pauses a process for a duration (see simgo.Process.Pause())
+	Pause Kind = "pause"
+	// Degrade sets the rate of a resource to a factor of its normal rate,
+	// for a duration or for good
+	Degrade Kind = "degrade"
+)
+
+// A Fault is a fault injected into a target at a simulation time.
+type Fault struct {
+	// Time is the simulation time of the fault
+	Time uint64
+	// Kind is the kind of fault
+	Kind Kind
+	// Target is the name of the process or resource
+	Target string
+	// Duration is how long a pause or a degradation lasts.  0 means that
+	// a degradation lasts for good.
+	Duration uint64
+	// Factor is the rate factor of a degradation, e.g., 0.5 for half the
+	// normal rate
+	Factor float64
+}
+
+// String formats the fault so that ParseFault() can parse it, e.g.:
+//
+//	degrade "disk" at 10 to 0.5 for 100
+func (f Fault) String() string {
+	s := fmt.Sprintf("%s %q at %d", f.Kind, f.Target, f.Time)
+	if f.Kind == Degrade {
+		s += fmt.Sprintf(" to %g", f.Factor)
+	}
+	if f.Duration > 0 {
+		s += fmt.Sprintf(" for %d", f.Duration)
+	}
+	return s
+}
+
+// ParseFault parses a fault formatted by Fault.String().
+func ParseFault(s string) (Fault, error) {
+	var f Fault
+	fields := strings.SplitN(s, " ", 2)
+	if len(fields) != 2 {
+		return f, errgo.Newf("invalid fault %q", s)
+	}
+	f.Kind = Kind(fields[0])
+	quoted, err := strconv.QuotedPrefix(fields[1])
+	if err != nil {
+		return f, errgo.Notef(err, "invalid target in fault %q", s)
+	}
+	f.Target, _ = strconv.Unquote(quoted)
+	rest := strings.Fields(fields[1][len(quoted):])
+	if len(rest)%2 != 0 {
+		return f, errgo.Newf("invalid fault %q", s)
+	}
+	for i := 0; i < len(rest); i += 2 {
+		switch rest[i] {
+		case "at":
+			f.Time, err = strconv.ParseUint(rest[i+1], 10, 64)
+		case "to":
+			f.Factor, err = strconv.ParseFloat(rest[i+1], 64)
+		case "for":
+			f.Duration, err = strconv.ParseUint(rest[i+1], 10, 64)
+		default:
+			err = errgo.Newf("unexpected %q", rest[i])
+		}
+		if err != nil {
+			return f, errgo.Notef(err, "invalid fault %q", s)
+		}
+	}
+	return f, nil
+}
+
+// FromTrace returns the faults recorded in a trace written in the JSONLines
+// format, in the order they were injected.
+func FromTrace(r io.Reader) ([]Fault, error) {
+	var faults []Fault
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var rec trace.Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, errgo.Notef(err, "cannot decode trace record")
+		}
+		s, ok := rec.Value.(string)
+		if rec.Name != EventName || !ok {
+			continue
+		}
+		f, err := ParseFault(s)
+		if err != nil {
+			return nil, errgo.Mask(err)
+		}
+		faults = append(faults, f)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errgo.Mask(err)
+	}
+	return faults, nil
+}
+
+// A Degradable is a resource whose rate can be degraded.  If it also has a
+// Rate() float64 method (like Throttle), the rate it had before it was
+// degraded is restored once the degradations are over, rather than its normal
+// rate.
+type Degradable interface {
+	// SetRate sets the rate of the resource to the provided factor of its
+	// normal rate.  A factor of 1 restores the normal rate.
+	SetRate(factor float64)
+}
+
+// A Throttle is a Degradable that scales delays, for models to pass their
+// service times through: at half the rate, service takes twice as long.
+type Throttle struct {
+	rate float64
+}
+
+var _ Degradable = (*Throttle)(nil)
+
+// NewThrottle returns a new Throttle at the normal rate.
+func NewThrottle() *Throttle {
+	return &Throttle{rate: 1}
+}
+
+// SetRate sets the rate to the provided factor of the normal rate.
+func (t *Throttle) SetRate(factor float64) {
+	t.rate = factor
+}
+
+// Rate returns the current rate factor.
+func (t *Throttle) Rate() float64 {
+	return t.rate
+}
+
+// Delay returns the delay d at the current rate, rounded up.
+func (t *Throttle) Delay(d uint64) uint64 {
+	if t.rate == 1 {
+		return d
+	}
+	return uint64(math.Ceil(float64(d) / t.rate))
+}
+
+// An Injector injects faults into the processes and resources registered
+// with it.
+type Injector struct {
+	env       *simgo.Environment
+	procs     map[string]*target
+	resources map[string]Degradable
+	// degraded holds the degradations in effect, by resource
+	degraded map[string]*degradations
+	injected []Fault
+}
+
+// target is a process registered with an Injector.
+type target struct {
+	fn   func(*simgo.Environment, *simgo.ProcComm) interface{}
+	proc *simgo.Process
+}
+
+// NewInjector returns a new Injector for the provided Environment.
+func NewInjector(env *simgo.Environment) *Injector {
+	return &Injector{
+		env:       env,
+		procs:     make(map[string]*target),
+		resources: make(map[string]Degradable),
+		degraded:  make(map[string]*degradations),
+	}
+}
+
+// Go starts a process with the provided name running fn, and registers it as
+// a target.  Restart faults run fn again in a new process with the same name.
+// Returns an error if the name is already registered.
+func (in *Injector) Go(name string, fn func(*simgo.Environment, *simgo.ProcComm) interface{}) (*simgo.Process, error) {
+	if in.registered(name) {
+		return nil, errgo.Newf("target %q is already registered", name)
+	}
+	t := &target{fn: fn}
+	in.procs[name] = t
+	in.start(name, t)
+	return t.proc, nil
+}
+
+// Process returns the current process of the target with the provided name,
+// or nil if there is none.
+func (in *Injector) Process(name string) *simgo.Process {
+	if t, ok := in.procs[name]; ok {
+		return t.proc
+	}
+	return nil
+}
+
+// Resource registers a resource as a target.  Returns an error if the name is
+// already registered.
+func (in *Injector) Resource(name string, r Degradable) error {
+	if in.registered(name) {
+		return errgo.Newf("target %q is already registered", name)
+	}
+	in.resources[name] = r
+	return nil
+}
+
+// Injected returns the faults injected so far, in order.
+func (in *Injector) Injected() []Fault {
+	return append([]Fault(nil), in.injected...)
+}
+
+// Schedule schedules the provided faults.  Returns an error, without
+// scheduling any fault, if a fault is in the past, is of an unknown kind, or
+// targets a process or resource that is not registered (or is not of the
+// right type for the fault).
+func (in *Injector) Schedule(faults ...Fault) error {
+	for _, f := range faults {
+		if err := in.check(f); err != nil {
+			return errgo.Mask(err)
+		}
+	}
+	for _, f := range faults {
+		f := f
+		to := simgo.NewTimeout(in.env, f.Time-in.env.Now, f, simgo.WithName(EventName))
+		to.AddCallback(func(*simgo.Event) {
+			in.inject(f)
+		})
+		to.Schedule(in.env)
+	}
+	return nil
+}
+
+// A Generator describes faults to generate randomly.
+type Generator struct {
+	// Interval is the distribution of the time between faults.  Intervals
+	// are at least 1, so that time advances between faults.
+	Interval random.Distribution
+	// Kinds are the kinds of faults, picked uniformly.  The target of a
+	// fault is picked uniformly among the registered targets of the
+	// right type.
+	Kinds []Kind
+	// Duration is the distribution of the duration of pauses and
+	// degradations
+	Duration random.Distribution
+	// Factor is the distribution of the rate factor of degradations
+	Factor random.Distribution
+	// Start and Until are the simulation times between which faults are
+	// generated
+	Start, Until uint64
+}
+
+// Generate generates faults as described by g, drawing from rng, and
+// schedules them.  The same stream and targets always generate the same
+// faults, which are returned so that they can be saved as a script.
+func (in *Injector) Generate(rng *random.Stream, g Generator) ([]Fault, error) {
+	if g.Interval == nil || len(g.Kinds) == 0 {
+		return nil, errgo.New("an interval and kinds are required")
+	}
+	var procs, resources []string
+	for name := range in.procs {
+		procs = append(procs, name)
+	}
+	for name := range in.resources {
+		resources = append(resources, name)
+	}
+	sort.Strings(procs)
+	sort.Strings(resources)
+
+	interval := func() uint64 {
+		if d := rng.Delay(g.Interval); d > 0 {
+			return d
+		}
+		return 1
+	}
+	var faults []Fault
+	for t := g.Start + interval(); t < g.Until; t += interval() {
+		f := Fault{Time: t, Kind: g.Kinds[rng.Intn(len(g.Kinds))]}
+		targets := procs
+		if f.Kind == Degrade {
+			targets = resources
+		}
+		if len(targets) == 0 {
+			return nil, errgo.Newf("no targets for %s faults", f.Kind)
+		}
+		f.Target = targets[rng.Intn(len(targets))]
+		if (f.Kind == Pause || f.Kind == Degrade) && g.Duration != nil {
+			f.Duration = rng.Delay(g.Duration)
+		}
+		if f.Kind == Pause && f.Duration == 0 {
+			f.Duration = 1
+		}
+		if f.Kind == Degrade {
+			f.Factor = 1
+			if g.Factor != nil {
+				f.Factor = g.Factor.Sample(rng)
+			}
+		}
+		faults = append(faults, f)
+	}
+	if err := in.Schedule(faults...); err != nil {
+		return nil, errgo.Mask(err)
+	}
+	return faults, nil
+}
+
+// check returns an error if the fault cannot be injected.
+func (in *Injector) check(f Fault) error {
+	if f.Time < in.env.Now {
+		return errgo.Newf("%s is in the past", f)
+	}
+	switch f.Kind {
+	case Crash, Restart, Pause:
+		if _, ok := in.procs[f.Target]; !ok {
+			return errgo.Newf("%s: no such process", f)
+		}
+		if f.Kind == Pause && f.Duration == 0 {
+			return errgo.Newf("%s: a pause needs a duration", f)
+		}
+	case Degrade:
+		if _, ok := in.resources[f.Target]; !ok {
+			return errgo.Newf("%s: no such resource", f)
+		}
+		if f.Factor <= 0 {
+			return errgo.Newf("%s: the rate factor must be positive", f)
+		}
+	default:
+		return errgo.Newf("%s: unknown kind of fault", f)
+	}
+	return nil
+}
+
+// inject injects a fault.  Faults on processes that have finished are
+// ignored, except for restarts.
+func (in *Injector) inject(f Fault) {
+	in.injected = append(in.injected, f)
+	switch f.Kind {
+	case Crash:
+		in.procs[f.Target].proc.Kill(f)
+	case Restart:
+		t := in.procs[f.Target]
+		t.proc.Kill(f)
+		in.start(f.Target, t)
+	case Pause:
+		in.procs[f.Target].proc.Pause(f.Duration)
+	case Degrade:
+		in.degrade(f)
+	}
+}
+
+// degradations are the degradations in effect on a resource.
+type degradations struct {
+	// base is the rate of the resource before it was degraded
+	base float64
+	// active holds the degradations in effect, in the order they were
+	// injected
+	active []*Fault
+}
+
+// degrade injects a degradation.  The most recent degradation in effect sets
+// the rate of the resource: when a degradation is over, the rate is set by
+// the degradation still in effect before it, or is restored once none is
+// left.
+func (in *Injector) degrade(f Fault) {
+	r := in.resources[f.Target]
+	d := in.degraded[f.Target]
+	if d == nil {
+		d = &degradations{base: 1}
+		if rr, ok := r.(interface{ Rate() float64 }); ok {
+			d.base = rr.Rate()
+		}
+		in.degraded[f.Target] = d
+	}
+	fp := &f
+	d.active = append(d.active, fp)
+	r.SetRate(f.Factor)
+	if f.Duration == 0 {
+		return
+	}
+	to := simgo.NewTimeout(in.env, f.Duration, f.Target, simgo.WithName("Recover"))
+	to.AddCallback(func(*simgo.Event) {
+		for i, active := range d.active {
+			if active == fp {
+				d.active = append(d.active[:i], d.active[i+1:]...)
+				break
+			}
+		}
+		if len(d.active) > 0 {
+			r.SetRate(d.active[len(d.active)-1].Factor)
+			return
+		}
+		r.SetRate(d.base)
+		delete(in.degraded, f.Target)
+	})
+	to.Schedule(in.env)
+}
+
+// start starts a new process for the target.
+func (in *Injector) start(name string, t *target) {
+	t.proc = simgo.NewProcess(in.env, simgo.ProcWrapper(in.env, t.fn, simgo.WithName(name)))
+	t.proc.Init()
+}
+
+// registered returns whether a target with the provided name is registered.
+func (in *Injector) registered(name string) bool {
+	_, isProc := in.procs[name]
+	_, isResource := in.resources[name]
+	return isProc || isResource
+}
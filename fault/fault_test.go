@@ -0,0 +1,180 @@
+package fault
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/trace"
+)
+
+// scenario sets up a server that handles a request every 10 units of time,
+// each taking 5 units through a throttled disk, and logs when requests are
+// done and when the server starts.
+func scenario(env *simgo.Environment, log *[]uint64) (*Injector, *Throttle) {
+	in := NewInjector(env)
+	disk := NewThrottle()
+	in.Resource("disk", disk)
+	in.Go("server", func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		*log = append(*log, 0)
+		for {
+			to := simgo.NewTimeout(env, 5+disk.Delay(5), nil)
+			to.Schedule(env)
+			pc.Yield(to.Event)
+			*log = append(*log, env.Now)
+		}
+	})
+	return in, disk
+}
+
+func TestSchedule(t *testing.T) {
+	env := simgo.NewEnvironment()
+	var log []uint64
+	in, disk := scenario(env, &log)
+	faults := []Fault{
+		{Time: 25, Kind: Crash, Target: "server"},
+		{Time: 40, Kind: Restart, Target: "server"},
+		{Time: 55, Kind: Pause, Target: "server", Duration: 20},
+		{Time: 80, Kind: Degrade, Target: "disk", Factor: 0.5, Duration: 30},
+	}
+	if err := in.Schedule(faults...); err != nil {
+		t.Fatalf("in.Schedule() = %v, want: nil", err)
+	}
+	var buf bytes.Buffer
+	tw := trace.NewWriter(env, &buf, trace.JSONLines)
+	env.Observer = tw
+	env.Run(uint64(130))
+	tw.Flush()
+	// The server crashes at 25, restarts at 40, is paused from 55 to 75,
+	// and takes 15 units per request from 80 to 110.
+	want := []uint64{0, 10, 20, 0, 50, 75, 85, 100, 115, 125}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want: %v", log, want)
+	}
+	if disk.Rate() != 1 {
+		t.Errorf("disk.Rate() = %v, want: 1 once recovered", disk.Rate())
+	}
+	if got := in.Injected(); !reflect.DeepEqual(got, faults) {
+		t.Errorf("in.Injected() = %v, want: %v", got, faults)
+	}
+	got, err := FromTrace(&buf)
+	if err != nil {
+		t.Fatalf("FromTrace() = %v, want: nil", err)
+	}
+	if !reflect.DeepEqual(got, faults) {
+		t.Errorf("FromTrace() = %v, want: %v", got, faults)
+	}
+}
+
+func TestScheduleErrors(t *testing.T) {
+	env := simgo.NewEnvironment()
+	var log []uint64
+	in, _ := scenario(env, &log)
+	for _, f := range []Fault{
+		{Time: 10, Kind: Crash, Target: "disk"},
+		{Time: 10, Kind: Degrade, Target: "server", Factor: 0.5},
+		{Time: 10, Kind: Degrade, Target: "disk"},
+		{Time: 10, Kind: Pause, Target: "server"},
+		{Time: 10, Kind: "flood", Target: "server"},
+	} {
+		if err := in.Schedule(f); err == nil {
+			t.Errorf("in.Schedule(%s) = nil, want: an error", f)
+		}
+	}
+	if _, err := in.Go("disk", nil); err == nil {
+		t.Errorf("in.Go() = nil, want: an error for a registered name")
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	g := Generator{
+		Interval: random.Exponential(50),
+		Kinds:    []Kind{Crash, Restart, Pause, Degrade},
+		Duration: random.Uniform(5, 20),
+		Factor:   random.Uniform(0.2, 0.8),
+		Until:    1000,
+	}
+	run := func() ([]Fault, []uint64) {
+		env := simgo.NewEnvironment()
+		var log []uint64
+		in, _ := scenario(env, &log)
+		faults, err := in.Generate(random.New(7), g)
+		if err != nil {
+			t.Fatalf("in.Generate() = %v, want: nil", err)
+		}
+		env.Run(uint64(1000))
+		return faults, log
+	}
+	faults, log := run()
+	if len(faults) < 5 {
+		t.Fatalf("len(faults) = %d, want: about 20", len(faults))
+	}
+	for _, f := range faults {
+		if f.Time >= g.Until || (f.Kind == Degrade) != (f.Target == "disk") {
+			t.Errorf("fault %s, want: a fault before %d on a target of the right type", f, g.Until)
+		}
+	}
+	faults2, log2 := run()
+	if !reflect.DeepEqual(faults, faults2) || !reflect.DeepEqual(log, log2) {
+		t.Errorf("runs differ with the same seed")
+	}
+}
+
+func TestParseFault(t *testing.T) {
+	for _, f := range []Fault{
+		{Time: 25, Kind: Crash, Target: "server"},
+		{Time: 55, Kind: Pause, Target: "web server", Duration: 20},
+		{Time: 80, Kind: Degrade, Target: "disk", Factor: 0.25},
+	} {
+		got, err := ParseFault(f.String())
+		if err != nil || got != f {
+			t.Errorf("ParseFault(%q) = %v, %v, want: %v, nil", f.String(), got, err, f)
+		}
+	}
+	for _, s := range []string{"crash", "crash server", `crash "server" at`, `crash "server" on 5`} {
+		if _, err := ParseFault(s); err == nil {
+			t.Errorf("ParseFault(%q) = nil error, want: an error", s)
+		}
+	}
+}
+
+func TestOverlappingDegradations(t *testing.T) {
+	env := simgo.NewEnvironment()
+	in := NewInjector(env)
+	disk := NewThrottle()
+	disk.SetRate(0.9)
+	in.Resource("disk", disk)
+	in.Schedule(
+		Fault{Time: 10, Kind: Degrade, Target: "disk", Factor: 0.5, Duration: 100},
+		Fault{Time: 20, Kind: Degrade, Target: "disk", Factor: 0.25, Duration: 30},
+	)
+	var rates []float64
+	for _, at := range []uint64{15, 30, 60, 120} {
+		env.Run(at)
+		rates = append(rates, disk.Rate())
+	}
+	// The second degradation is over at 50, while the first is still in
+	// effect, and the rate from before is restored at 110.
+	if want := []float64{0.5, 0.25, 0.5, 0.9}; !reflect.DeepEqual(rates, want) {
+		t.Errorf("rates = %v, want: %v", rates, want)
+	}
+}
+
+func TestGenerateZeroInterval(t *testing.T) {
+	env := simgo.NewEnvironment()
+	var log []uint64
+	in, _ := scenario(env, &log)
+	faults, err := in.Generate(random.New(1), Generator{
+		Interval: random.Constant(0),
+		Kinds:    []Kind{Crash},
+		Until:    10,
+	})
+	if err != nil {
+		t.Fatalf("in.Generate() = %v, want: nil", err)
+	}
+	if len(faults) != 9 || faults[0].Time != 1 || faults[8].Time != 9 {
+		t.Errorf("faults = %v, want: one at each time from 1 to 9", faults)
+	}
+}
@@ -0,0 +1,49 @@
+package simgo
+
+import (
+	"github.com/juju/errgo"
+)
+
+// Fork returns a new, independent Environment in the current state of this
+// one, e.g., to try several policies from the same point of a simulation and
+// compare the outcomes.  It must be called between calls to Run() or Step().
+//
+// The clock, the event ID counter and the scheduled events (with their
+// times, priorities and IDs) are copied, so the fork continues exactly like
+// the original would, unless the model is changed.  Forking does not change
+// the original Environment.
+//
+// What cannot be copied is everything that lives in goroutines and closures.
+// Process functions are coroutines driven through their ProcComm, and a
+// running goroutine cannot be duplicated, so the fork gets no processes,
+// events or callbacks from the original.  Instead, the model takes a
+// snapshot of its state with model.Snapshot() and the provided RestoreFunc
+// rebuilds the model in the fork, exactly as for Restore(): it starts new
+// processes that pick up where the original ones were and schedules the
+// events they were waiting for, which take over the times, priorities and
+// IDs of the original events.  The RestoreFunc may also change the model,
+// e.g., by capturing the policy to try.
+//
+// In particular:
+//
+//   - Model state (counters, queues, random stream states, ...) is forked
+//     only as far as it is included in the snapshot.  Values shared with the
+//     original (pointers, maps, slices) must be copied by the RestoreFunc.
+//   - Events that are not scheduled (e.g., a plain Event that a process
+//     waits for until another one triggers it) must be recreated and linked
+//     by the RestoreFunc.
+//   - The Observer is not copied.  Set one on the fork if needed.
+//
+// Fork returns an error if the snapshot or the rebuilt model do not match
+// the scheduled events.
+func (env *Environment) Fork(model Snapshotter, restore RestoreFunc) (*Environment, error) {
+	cp, err := env.checkpoint(model)
+	if err != nil {
+		return nil, err
+	}
+	fork, err := restoreCheckpoint(cp, restore)
+	if err != nil {
+		return nil, errgo.Notef(err, "cannot fork environment")
+	}
+	return fork, nil
+}
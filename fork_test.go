@@ -0,0 +1,41 @@
+package simgo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bgmerrell/simgo/random"
+)
+
+func TestFork(t *testing.T) {
+	env := NewEnvironment()
+	m := newTickModel(env, "a", "b")
+	env.Run(50)
+	logged := len(m.log)
+
+	var same, busier *tickModel
+	sameEnv, err := env.Fork(m, restoreTickModel(&same, "a", "b"))
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	busierEnv, err := env.Fork(m, restoreTickModel(&busier, "a", "b"))
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	// Try another policy in one of the branches.
+	busier.streams["c"] = random.New(2).Named("c")
+	busier.start("c", false)
+
+	env.Run(200)
+	sameEnv.Run(200)
+	busierEnv.Run(200)
+	if !reflect.DeepEqual(same.log, m.log[logged:]) {
+		t.Errorf("same.log = %v, want: %v", same.log, m.log[logged:])
+	}
+	if busier.ticks["c"] == 0 {
+		t.Errorf("busier.ticks = %v, want: ticks for c", busier.ticks)
+	}
+	if m.ticks["c"] != 0 {
+		t.Errorf("m.ticks = %v, want: no ticks for c in the original", m.ticks)
+	}
+}
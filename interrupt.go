@@ -0,0 +1,107 @@
+package simgo
+
+import (
+	"fmt"
+
+	"github.com/juju/errgo"
+)
+
+// An Interrupt is the value an interrupted process is resumed with (see
+// Process.Interrupt()), and the error of a process that was killed (see
+// Process.Kill()).
+type Interrupt struct {
+	// Cause is the cause provided to Interrupt() or Kill()
+	Cause interface{}
+}
+
+func (i *Interrupt) Error() string {
+	return fmt.Sprintf("interrupted: %v", i.Cause)
+}
+
+// killSignal is the value a killed process is resumed with.  Yield() panics
+// with it, and ProcWrapper() recovers from it.
+type killSignal struct{}
+
+// Interrupt resumes the process with an *Interrupt holding the provided
+// cause, instead of the value of the event it is waiting for.  The process
+// stops waiting for that event, but may yield it again.  The interrupt
+// happens at the current simulation time, once the process is suspended, and
+// is ignored if the process has not started or has finished by then.
+// Returns an error if the process has finished.
+func (p *Process) Interrupt(cause interface{}) error {
+	return p.interrupt("Interrupt", cause, false)
+}
+
+// Kill terminates the process: the process function unwinds from the call to
+// ProcComm.Yield() it is suspended in, running its deferred calls, and the
+// Process event fails with an *Interrupt holding the provided cause.  A
+// process that has not started yet is killed before running any of its code.
+// Like Interrupt(), Kill() happens once the process is suspended, and returns
+// an error if the process has finished.
+func (p *Process) Kill(cause interface{}) error {
+	return p.interrupt("Kill", cause, true)
+}
+
+// Pause suspends the process for the provided delay: the process is not
+// resumed before the delay has elapsed, even if the event it is waiting for
+// is processed in the meantime (it is then resumed with the event's value
+// once the delay has elapsed).  The pause starts once the process is
+// suspended, is ignored if the process is not waiting for an event by then,
+// and ends early if the process is interrupted or killed.  Returns an error
+// if the process has finished.
+func (p *Process) Pause(delay uint64) error {
+	if p.finished {
+		return errgo.Newf("%s has finished", p)
+	}
+	e := newEvent(p.env, KindInterrupt, []EventOption{WithName("Pause")})
+	e.callbacks = append(e.callbacks, func(*Event) {
+		if p.finished || p.target == nil {
+			return
+		}
+		target := p.target
+		p.target = nil
+		p.detached++
+		detached := p.detached
+		to := NewTimeout(p.env, delay, nil, WithName("Resume"))
+		to.callbacks = append(to.callbacks, func(*Event) {
+			if p.finished || p.detached != detached {
+				// The process was interrupted or killed.
+				return
+			}
+			if target.Processed() {
+				p.resume(target)
+				return
+			}
+			// The process is still registered with the event.
+			p.target = target
+		})
+		to.Schedule(p.env)
+	})
+	e.trigger(delay, true)
+	p.env.Schedule(e, PriorityUrgent, 0)
+	return nil
+}
+
+// interrupt schedules an event that resumes the process with an *Interrupt
+// holding the provided cause, or that kills the process.
+func (p *Process) interrupt(name string, cause interface{}, kill bool) error {
+	if p.finished {
+		return errgo.Newf("%s has finished", p)
+	}
+	intr := &Interrupt{cause}
+	e := newEvent(p.env, KindInterrupt, []EventOption{WithName(name)})
+	e.callbacks = append(e.callbacks, func(e *Event) {
+		if p.finished || (!kill && !p.started) {
+			return
+		}
+		if kill {
+			p.killed = intr
+		}
+		p.target = nil
+		p.detached++
+		p.resume(e)
+	})
+	e.trigger(intr, true)
+	p.env.Schedule(e, PriorityUrgent, 0)
+	return nil
+}
@@ -0,0 +1,118 @@
+package simgo
+
+import (
+	"reflect"
+	"testing"
+)
+
+// sleeper returns a process that sleeps for delay, appending what happens to
+// log, and tries again once if it is interrupted.
+func sleeper(env *Environment, delay uint64, log *[]interface{}) *Process {
+	p := NewProcess(env, ProcWrapper(env, func(env *Environment, pc *ProcComm) interface{} {
+		defer func() {
+			*log = append(*log, "deferred", env.Now)
+		}()
+		for i := 0; i < 2; i++ {
+			to := NewTimeout(env, delay, "woke")
+			to.Schedule(env)
+			v := pc.Yield(to.Event)
+			if intr, ok := v.(*Interrupt); ok {
+				*log = append(*log, intr.Cause, env.Now)
+				continue
+			}
+			*log = append(*log, v, env.Now)
+			return "done"
+		}
+		return "gave up"
+	}, WithName("sleeper")))
+	p.Init()
+	return p
+}
+
+// at calls fn at the provided time.
+func at(env *Environment, t uint64, fn func()) {
+	to := NewTimeout(env, t-env.Now, nil)
+	to.AddCallback(func(*Event) { fn() })
+	to.Schedule(env)
+}
+
+func TestInterrupt(t *testing.T) {
+	env := NewEnvironment()
+	var log []interface{}
+	p := sleeper(env, 10, &log)
+	at(env, 4, func() { p.Interrupt("spam") })
+	env.Run(nil)
+	// The timeout of the first sleep is ignored.
+	want := []interface{}{"spam", uint64(4), "woke", uint64(14), "deferred", uint64(14)}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want: %v", log, want)
+	}
+	if got := p.ReturnValue(); got != "done" {
+		t.Errorf("p.ReturnValue() = %v, want: done", got)
+	}
+	if err := p.Interrupt("eggs"); err == nil {
+		t.Errorf("p.Interrupt() = nil, want: an error once finished")
+	}
+}
+
+func TestKill(t *testing.T) {
+	env := NewEnvironment()
+	var log []interface{}
+	p := sleeper(env, 10, &log)
+	at(env, 4, func() { p.Kill("crash") })
+	env.Run(nil)
+	want := []interface{}{"deferred", uint64(4)}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want: %v", log, want)
+	}
+	val, _ := p.Value()
+	if intr, ok := val.(*Interrupt); p.OK() || !ok || intr.Cause != "crash" {
+		t.Errorf("p.Value() = %v (ok: %v), want: a failure with the cause", val, p.OK())
+	}
+	if got := p.ReturnValue(); got != nil {
+		t.Errorf("p.ReturnValue() = %v, want: nil", got)
+	}
+
+	// A process can be killed before it starts.
+	env = NewEnvironment()
+	ran := false
+	p = NewProcess(env, ProcWrapper(env, func(env *Environment, pc *ProcComm) interface{} {
+		ran = true
+		return nil
+	}))
+	p.Kill("crash")
+	p.Init()
+	env.Run(nil)
+	if ran || p.OK() {
+		t.Errorf("ran = %v, p.OK() = %v, want: false, false", ran, p.OK())
+	}
+}
+
+func TestPause(t *testing.T) {
+	env := NewEnvironment()
+	var log []interface{}
+	sleeper(env, 10, &log)
+	p := sleeper(env, 10, &log)
+	at(env, 4, func() { p.Pause(20) })
+	env.Run(nil)
+	// The second sleeper sees its timeout once the pause is over.
+	want := []interface{}{
+		"woke", uint64(10), "deferred", uint64(10),
+		"woke", uint64(24), "deferred", uint64(24),
+	}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want: %v", log, want)
+	}
+
+	// An interrupt ends the pause.
+	env = NewEnvironment()
+	log = nil
+	p = sleeper(env, 10, &log)
+	at(env, 4, func() { p.Pause(100) })
+	at(env, 6, func() { p.Interrupt("spam") })
+	env.Run(nil)
+	want = []interface{}{"spam", uint64(6), "woke", uint64(16), "deferred", uint64(16)}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want: %v", log, want)
+	}
+}
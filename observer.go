@@ -0,0 +1,29 @@
+package simgo
+
+// An Observer is notified of what an Environment does while it runs a
+// simulation.  Tracing is enabled by setting Environment.Observer; when it is
+// nil the Environment skips all notifications.
+//
+// Observer methods are called synchronously from the simulation and must not
+// schedule, trigger or yield events themselves.
+type Observer interface {
+	// EventScheduled is called when an event is added to the event queue
+	// to be processed at the provided time.
+	EventScheduled(event *Event, time uint64, priority int, eid EventID)
+	// EventProcessed is called when an event is taken off the event queue,
+	// just before its callbacks are called.
+	EventProcessed(event *Event, time uint64, priority int, eid EventID)
+	// ProcessStarted is called when a process function first runs.
+	ProcessStarted(p *Process)
+	// ProcessResumed is called when a process function is resumed with
+	// the value of the provided event.
+	ProcessResumed(p *Process, event *Event)
+	// ProcessSuspended is called when a process function yields an event
+	// that has not yet been processed and waits for it.
+	ProcessSuspended(p *Process, event *Event)
+	// ProcessFinished is called when a process function returns.
+	ProcessFinished(p *Process)
+	// ConditionTriggered is called when the provided event causes a
+	// condition to be triggered (either successfully or not).
+	ConditionTriggered(c *Condition, event *Event)
+}
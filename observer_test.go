@@ -0,0 +1,75 @@
+package simgo
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// recordingObserver records a line for each Observer notification.
+type recordingObserver struct {
+	lines []string
+}
+
+func (o *recordingObserver) EventScheduled(event *Event, time uint64, priority int, eid EventID) {
+	o.lines = append(o.lines, fmt.Sprintf("scheduled %d %d %d", time, priority, eid))
+}
+
+func (o *recordingObserver) EventProcessed(event *Event, time uint64, priority int, eid EventID) {
+	o.lines = append(o.lines, fmt.Sprintf("processed %d %d %d", time, priority, eid))
+}
+
+func (o *recordingObserver) ProcessStarted(p *Process) {
+	o.lines = append(o.lines, "started")
+}
+
+func (o *recordingObserver) ProcessResumed(p *Process, event *Event) {
+	o.lines = append(o.lines, "resumed")
+}
+
+func (o *recordingObserver) ProcessSuspended(p *Process, event *Event) {
+	o.lines = append(o.lines, "suspended")
+}
+
+func (o *recordingObserver) ProcessFinished(p *Process) {
+	o.lines = append(o.lines, "finished")
+}
+
+func (o *recordingObserver) ConditionTriggered(c *Condition, event *Event) {
+	o.lines = append(o.lines, "condition")
+}
+
+func TestObserver(t *testing.T) {
+	env := NewEnvironment()
+	obs := &recordingObserver{}
+	env.Observer = obs
+	p := NewProcess(env, ProcWrapper(env, func(env *Environment, pc *ProcComm) interface{} {
+		t1 := NewTimeout(env, 3, nil)
+		t1.Schedule(env)
+		pc.Yield(AnyOf(env, []*Event{t1.Event}).Event)
+		return nil
+	}))
+	p.Init()
+	if _, err := env.Run(nil); err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+
+	want := []string{
+		"scheduled 0 0 1", // process initialization
+		"processed 0 0 1",
+		"started",
+		"scheduled 3 1 2", // timeout
+		"suspended",
+		"processed 3 1 2",
+		"scheduled 3 1 3", // condition
+		"condition",
+		"processed 3 1 3",
+		"resumed",
+		"finished",
+		"scheduled 3 1 4", // process
+		"processed 3 1 4",
+	}
+	if !reflect.DeepEqual(obs.lines, want) {
+		t.Errorf("obs.lines = %#v, want: %#v", obs.lines, want)
+	}
+}
@@ -20,6 +20,8 @@ type ProcComm struct {
 	state coroutineState
 	// returnValue is the value returned by the coroutine
 	returnValue interface{}
+	// opts are the options for the Process created for the coroutine
+	opts []EventOption
 }
 
 // NewProcComm returns a new ProcComm with initialized channels and suspended
@@ -36,6 +38,10 @@ func NewProcComm() *ProcComm {
 // stateRunning. Subsequent calls to Yield() communicate the provided event
 // such that it can be read from Resume().  Yield() communicates over
 // unbuffered channels and may block accordingly.
+//
+// If the process is killed while suspended, Yield() panics with a value that
+// ProcWrapper() recovers from, so the process function unwinds (running its
+// deferred calls) without returning.
 func (pc *ProcComm) Yield(event *Event) interface{} {
 	if pc.state == stateRunning {
 		pc.state = stateSuspended
@@ -43,6 +49,9 @@ func (pc *ProcComm) Yield(event *Event) interface{} {
 	}
 	resumeVal := <-pc.resumeCh
 	pc.state = stateRunning
+	if k, ok := resumeVal.(killSignal); ok {
+		panic(k)
+	}
 	return resumeVal
 }
 
@@ -58,7 +67,9 @@ func (pc *ProcComm) Resume(x interface{}) (*Event, bool) {
 }
 
 // Finish finalizes the underlying channels such that the process can finish.
+// The return value is set before the yield channel is closed, so that it is
+// visible to the process once Resume() returns.
 func (pc *ProcComm) Finish(x interface{}) {
-	close(pc.yieldCh)
 	pc.returnValue = x
+	close(pc.yieldCh)
 }
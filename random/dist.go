@@ -0,0 +1,219 @@
+package random
+
+import (
+	"math"
+	"sort"
+)
+
+// A Distribution is a probability distribution that random variates can be
+// drawn from.  Use Stream.Delay() to draw simulation delays.
+type Distribution interface {
+	// Sample draws a value from the distribution using the provided stream.
+	Sample(s *Stream) float64
+}
+
+// DistributionFunc adapts an ordinary function to a Distribution.
+type DistributionFunc func(s *Stream) float64
+
+// Sample calls f(s).
+func (f DistributionFunc) Sample(s *Stream) float64 {
+	return f(s)
+}
+
+// Constant returns a distribution that always returns the provided value.
+func Constant(value float64) Distribution {
+	return DistributionFunc(func(*Stream) float64 {
+		return value
+	})
+}
+
+// Uniform returns the continuous uniform distribution on (min, max).
+func Uniform(min, max float64) Distribution {
+	return DistributionFunc(func(s *Stream) float64 {
+		return min + (max-min)*s.Float64()
+	})
+}
+
+// Exponential returns the exponential distribution with the provided mean.
+func Exponential(mean float64) Distribution {
+	return DistributionFunc(func(s *Stream) float64 {
+		return -mean * math.Log(s.Float64())
+	})
+}
+
+// Erlang returns the Erlang distribution with the provided shape, k, and
+// mean, i.e., the sum of k exponential variates with mean mean/k.  It panics
+// if k < 1.
+func Erlang(k int, mean float64) Distribution {
+	if k < 1 {
+		panic("random: Erlang shape must be at least 1")
+	}
+	return DistributionFunc(func(s *Stream) float64 {
+		prod := 1.0
+		for i := 0; i < k; i++ {
+			prod *= s.Float64()
+		}
+		return -mean / float64(k) * math.Log(prod)
+	})
+}
+
+// Normal returns the normal distribution with the provided mean and standard
+// deviation.
+func Normal(mean, stdDev float64) Distribution {
+	return DistributionFunc(func(s *Stream) float64 {
+		return mean + stdDev*standardNormal(s)
+	})
+}
+
+// Lognormal returns the lognormal distribution whose logarithm is normally
+// distributed with the provided mean, mu, and standard deviation, sigma.
+func Lognormal(mu, sigma float64) Distribution {
+	return DistributionFunc(func(s *Stream) float64 {
+		return math.Exp(mu + sigma*standardNormal(s))
+	})
+}
+
+// Gamma returns the gamma distribution with the provided shape and scale.
+// It panics if shape or scale are not positive.
+func Gamma(shape, scale float64) Distribution {
+	if shape <= 0 || scale <= 0 {
+		panic("random: Gamma shape and scale must be positive")
+	}
+	return DistributionFunc(func(s *Stream) float64 {
+		return scale * standardGamma(s, shape)
+	})
+}
+
+// Weibull returns the Weibull distribution with the provided shape and
+// scale.
+func Weibull(shape, scale float64) Distribution {
+	return DistributionFunc(func(s *Stream) float64 {
+		return scale * math.Pow(-math.Log(s.Float64()), 1/shape)
+	})
+}
+
+// Triangular returns the triangular distribution on (min, max) with the
+// provided mode.  It panics unless min <= mode <= max and min < max.
+func Triangular(min, mode, max float64) Distribution {
+	if !(min <= mode && mode <= max && min < max) {
+		panic("random: Triangular requires min <= mode <= max and min < max")
+	}
+	split := (mode - min) / (max - min)
+	return DistributionFunc(func(s *Stream) float64 {
+		u := s.Float64()
+		if u < split {
+			return min + math.Sqrt(u*(max-min)*(mode-min))
+		}
+		return max - math.Sqrt((1-u)*(max-min)*(max-mode))
+	})
+}
+
+// Empirical returns the continuous distribution of the provided
+// observations: values are drawn from the piecewise linear interpolation of
+// their empirical distribution function.  It panics if there are no
+// observations.
+func Empirical(observations []float64) Distribution {
+	if len(observations) == 0 {
+		panic("random: Empirical requires observations")
+	}
+	sorted := append([]float64(nil), observations...)
+	sort.Float64s(sorted)
+	return DistributionFunc(func(s *Stream) float64 {
+		if len(sorted) == 1 {
+			return sorted[0]
+		}
+		pos := s.Float64() * float64(len(sorted)-1)
+		i := int(pos)
+		return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+	})
+}
+
+// Discrete returns the discrete distribution over the provided values, each
+// drawn with a probability proportional to its weight.  It panics if the
+// values and weights differ in length or if the weights are not positive.
+func Discrete(values, weights []float64) Distribution {
+	if len(values) == 0 || len(values) != len(weights) {
+		panic("random: Discrete requires one weight per value")
+	}
+	cumulative := make([]float64, len(weights))
+	total := 0.0
+	for i, w := range weights {
+		if w < 0 {
+			panic("random: Discrete weights must not be negative")
+		}
+		total += w
+		cumulative[i] = total
+	}
+	if total <= 0 {
+		panic("random: Discrete weights must not all be zero")
+	}
+	return DistributionFunc(func(s *Stream) float64 {
+		u := s.Float64() * total
+		i := sort.SearchFloat64s(cumulative, u)
+		if i == len(values) {
+			i--
+		}
+		return values[i]
+	})
+}
+
+// A PoissonProcess generates the delays between arrivals of a Poisson
+// process.  Arrival times are tracked exactly, so rounding each delay to a
+// simulation delay does not accumulate error.
+type PoissonProcess struct {
+	s        *Stream
+	interval Distribution
+	// at is the exact time of the last arrival, relative to the first call
+	// to Next()
+	at float64
+	// delivered is the time of the last arrival rounded to a delay
+	delivered uint64
+}
+
+// NewPoissonProcess returns a new PoissonProcess with the provided rate of
+// arrivals per unit of simulation time that draws from s.
+func NewPoissonProcess(s *Stream, rate float64) *PoissonProcess {
+	return &PoissonProcess{
+		s:        s,
+		interval: Exponential(1 / rate),
+	}
+}
+
+// Next returns the delay until the next arrival.
+func (pp *PoissonProcess) Next() uint64 {
+	pp.at += pp.interval.Sample(pp.s)
+	at := toDelay(pp.at)
+	delay := at - pp.delivered
+	pp.delivered = at
+	return delay
+}
+
+// standardNormal draws a standard normal variate using the Box-Muller
+// transform.  Exactly two numbers are drawn from the stream.
+func standardNormal(s *Stream) float64 {
+	u1, u2 := s.Float64(), s.Float64()
+	return math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+}
+
+// standardGamma draws a gamma variate with the provided shape and a scale of
+// one using the method of Marsaglia and Tsang (2000).
+func standardGamma(s *Stream, shape float64) float64 {
+	if shape < 1 {
+		// Boost the shape and scale the result back down.
+		return standardGamma(s, shape+1) * math.Pow(s.Float64(), 1/shape)
+	}
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x := standardNormal(s)
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u := s.Float64()
+		if math.Log(u) < 0.5*x*x+d-d*v+d*math.Log(v) {
+			return d * v
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package random
+
+import (
+	"math"
+	"testing"
+)
+
+func TestStreamReference(t *testing.T) {
+	// Reference output of xoshiro256** for the state {1, 2, 3, 4}
+	s := &Stream{state: [4]uint64{1, 2, 3, 4}}
+	for i, want := range []uint64{11520, 0, 1509978240, 1215971899390074240} {
+		if got := s.Uint64(); got != want {
+			t.Errorf("Draw %d: got %d, want: %d", i+1, got, want)
+		}
+	}
+}
+
+func TestNamedStreams(t *testing.T) {
+	a := New(42)
+	arrivals := a.Named("arrivals")
+	service := a.Named("service")
+
+	// Creating another stream, in a different order, must not change the
+	// existing streams.
+	b := New(42)
+	b.Named("breakdowns").Uint64()
+	service2 := b.Named("service")
+	arrivals2 := b.Named("arrivals")
+	for i := 0; i < 10; i++ {
+		if arrivals.Uint64() != arrivals2.Uint64() || service.Uint64() != service2.Uint64() {
+			t.Fatalf("Draw %d: named streams differ", i+1)
+		}
+	}
+	if New(42).Named("arrivals").Uint64() == New(43).Named("arrivals").Uint64() {
+		t.Errorf("streams for different seeds are equal")
+	}
+}
+
+func TestSubstream(t *testing.T) {
+	s := New(1)
+	s.Uint64()
+	want := New(1)
+	want.Jump()
+	want.Jump()
+	sub := s.Substream(1)
+	for i := 0; i < 10; i++ {
+		if got, want := sub.Uint64(), want.Uint64(); got != want {
+			t.Fatalf("Draw %d: got %d, want: %d", i+1, got, want)
+		}
+	}
+}
+
+// sampleMean returns the mean of n draws from d.
+func sampleMean(d Distribution, s *Stream, n int) float64 {
+	sum := 0.0
+	for i := 0; i < n; i++ {
+		sum += d.Sample(s)
+	}
+	return sum / float64(n)
+}
+
+func TestDistributionMeans(t *testing.T) {
+	const n = 200000
+	s := New(7)
+	for _, test := range []struct {
+		name string
+		d    Distribution
+		mean float64
+	}{
+		{"Uniform", Uniform(2, 4), 3},
+		{"Exponential", Exponential(5), 5},
+		{"Erlang", Erlang(3, 6), 6},
+		{"Normal", Normal(10, 2), 10},
+		{"Lognormal", Lognormal(0, 0.5), math.Exp(0.125)},
+		{"Gamma", Gamma(2.5, 2), 5},
+		{"Gamma<1", Gamma(0.5, 2), 1},
+		{"Weibull", Weibull(2, 1), math.Gamma(1.5)},
+		{"Triangular", Triangular(0, 1, 5), 2},
+		{"Empirical", Empirical([]float64{4, 0, 2}), 2},
+		{"Discrete", Discrete([]float64{1, 10}, []float64{3, 1}), 3.25},
+	} {
+		got := sampleMean(test.d, s, n)
+		if math.Abs(got-test.mean) > 0.02*test.mean {
+			t.Errorf("%s: mean = %g, want: %g", test.name, got, test.mean)
+		}
+	}
+}
+
+func TestPoissonProcess(t *testing.T) {
+	const n = 100000
+	pp := NewPoissonProcess(New(3).Named("arrivals"), 0.3)
+	var total uint64
+	for i := 0; i < n; i++ {
+		total += pp.Next()
+	}
+	if got, want := float64(total)/n, 1/0.3; math.Abs(got-want) > 0.02*want {
+		t.Errorf("mean delay = %g, want: %g", got, want)
+	}
+}
+
+func TestAntithetic(t *testing.T) {
+	s := New(9).Named("service")
+	a := New(9).Antithetic().Named("service")
+	for i := 0; i < 10; i++ {
+		u, v := s.Float64(), a.Float64()
+		if math.Abs(u+v-1) > 1e-15 {
+			t.Fatalf("Draw %d: u + v = %g, want: 1", i+1, u+v)
+		}
+	}
+}
+
+func TestMarshalBinary(t *testing.T) {
+	s := New(10).Antithetic().Named("arrivals")
+	s.Uint64()
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	restored := &Stream{}
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	for i := 0; i < 10; i++ {
+		if got, want := restored.Uint64(), s.Uint64(); got != want {
+			t.Fatalf("Draw %d: got %d, want: %d", i+1, got, want)
+		}
+	}
+	if restored.Substream(1).Uint64() != s.Substream(1).Uint64() {
+		t.Errorf("substreams differ, want: same origin")
+	}
+	if err := restored.UnmarshalBinary(data[1:]); err == nil {
+		t.Errorf("err = nil, want: non-nil for a truncated state")
+	}
+}
@@ -0,0 +1,246 @@
+// Package random provides reproducible random number streams and random
+// variate generators for simgo simulations.
+//
+// All randomness in a model should come from one master Stream created with
+// a seed.  Model components draw from their own named substreams, so they do
+// not interfere with each other, and adding a new named stream does not
+// change the numbers drawn by the existing ones:
+//
+//	master := random.New(42)
+//	arrivals := master.Named("arrivals")
+//	service := master.Named("service")
+//	delay := arrivals.Delay(random.Exponential(5))
+//
+// Streams can also be split into numbered substreams (e.g., one per
+// replication) using jump-ahead.
+//
+// Because each component draws from its own stream, two configurations of a
+// model run with the same seed see the same random numbers in the same
+// components (common random numbers).  An Antithetic() stream draws the
+// complements of the numbers drawn by the original stream.
+package random
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"math/rand"
+
+	"github.com/juju/errgo"
+)
+
+// jumpPoly is the xoshiro256 jump polynomial, which advances a stream by
+// 2^128 draws.
+var jumpPoly = [4]uint64{
+	0x180ec6d33cfd0aba, 0xd5a61266f0c9392c,
+	0xa9582618e03fc9aa, 0x39abdc4529b1661c,
+}
+
+// A Stream is a stream of pseudo-random numbers generated with the
+// xoshiro256** algorithm.  A Stream is not safe for concurrent use.
+type Stream struct {
+	// state is the current generator state
+	state [4]uint64
+	// origin is the state the stream started with, from which named
+	// streams and substreams are derived
+	origin [4]uint64
+	// antithetic is whether the stream's draws are complemented
+	antithetic bool
+	// tap, if set, is called with every number drawn and returns the
+	// number to use instead
+	tap func(x uint64) uint64
+}
+
+// Stream implements the math/rand Source64 interface so it can be used with
+// rand.New().
+var _ rand.Source64 = (*Stream)(nil)
+
+// New returns a new master Stream for the provided seed.
+func New(seed uint64) *Stream {
+	s := &Stream{}
+	s.seed(seed)
+	return s
+}
+
+// Named returns the substream with the provided name.  The substream only
+// depends on this stream's origin and the name, so named streams can be
+// created in any order.  Each call returns a new Stream at the start of the
+// substream, so a model component should keep the Stream it draws from.
+func (s *Stream) Named(name string) *Stream {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	seed := h.Sum64()
+	for _, word := range s.origin {
+		seed = splitMix64(&seed) ^ word
+	}
+	named := New(seed)
+	named.antithetic = s.antithetic
+	named.tap = s.tap
+	return named
+}
+
+// Substream returns the k-th numbered substream, which starts (k+1)*2^128
+// draws after this stream's origin.  Substreams do not overlap unless more
+// than 2^128 numbers are drawn from one of them.
+func (s *Stream) Substream(k uint64) *Stream {
+	sub := &Stream{state: s.origin, antithetic: s.antithetic, tap: s.tap}
+	for i := uint64(0); i <= k; i++ {
+		sub.Jump()
+	}
+	sub.origin = sub.state
+	return sub
+}
+
+// Antithetic returns a copy of the stream that draws the complement of every
+// number the stream would draw, e.g., 1-u instead of u for Float64().  The
+// substreams of an antithetic stream are antithetic as well.
+func (s *Stream) Antithetic() *Stream {
+	a := *s
+	a.antithetic = !s.antithetic
+	return &a
+}
+
+// Tap sets a function that is called with every number drawn from the
+// stream, and from streams derived from it afterwards, and returns the number
+// to use instead.  It is used to record and replay the draws of a run.  A nil
+// function removes the tap.
+func (s *Stream) Tap(fn func(x uint64) uint64) {
+	s.tap = fn
+}
+
+// Jump advances the stream by 2^128 draws.
+func (s *Stream) Jump() {
+	var jumped [4]uint64
+	for _, poly := range jumpPoly {
+		for b := uint(0); b < 64; b++ {
+			if poly&(1<<b) != 0 {
+				for i := range jumped {
+					jumped[i] ^= s.state[i]
+				}
+			}
+			s.nextRaw()
+		}
+	}
+	s.state = jumped
+}
+
+// Uint64 returns a pseudo-random 64-bit integer.
+func (s *Stream) Uint64() uint64 {
+	return s.next()
+}
+
+// Float64 returns a pseudo-random number in the open interval (0, 1).
+func (s *Stream) Float64() float64 {
+	return (float64(s.next()>>11) + 0.5) / (1 << 53)
+}
+
+// Intn returns a pseudo-random number in [0, n).  It panics if n <= 0.
+func (s *Stream) Intn(n int) int {
+	if n <= 0 {
+		panic("random: invalid argument to Intn")
+	}
+	hi, _ := bits.Mul64(s.next(), uint64(n))
+	return int(hi)
+}
+
+// Int63 returns a non-negative pseudo-random 63-bit integer.
+func (s *Stream) Int63() int64 {
+	return int64(s.next() >> 1)
+}
+
+// Seed resets the stream to the state of New(uint64(seed)).
+func (s *Stream) Seed(seed int64) {
+	s.seed(uint64(seed))
+}
+
+// Delay draws a value from the provided distribution and rounds it to a
+// simulation delay.  Negative values are returned as a zero delay.
+func (s *Stream) Delay(d Distribution) uint64 {
+	return toDelay(d.Sample(s))
+}
+
+// stateSize is the size of a marshaled Stream.
+const stateSize = 8*8 + 1
+
+// MarshalBinary returns the state of the stream, e.g., to save it in a model
+// snapshot.
+func (s *Stream) MarshalBinary() ([]byte, error) {
+	data := make([]byte, stateSize)
+	for i, word := range s.state {
+		binary.LittleEndian.PutUint64(data[8*i:], word)
+	}
+	for i, word := range s.origin {
+		binary.LittleEndian.PutUint64(data[32+8*i:], word)
+	}
+	if s.antithetic {
+		data[64] = 1
+	}
+	return data, nil
+}
+
+// UnmarshalBinary restores a state returned by MarshalBinary().
+func (s *Stream) UnmarshalBinary(data []byte) error {
+	if len(data) != stateSize {
+		return errgo.New("invalid stream state")
+	}
+	for i := range s.state {
+		s.state[i] = binary.LittleEndian.Uint64(data[8*i:])
+	}
+	for i := range s.origin {
+		s.origin[i] = binary.LittleEndian.Uint64(data[32+8*i:])
+	}
+	s.antithetic = data[64] == 1
+	return nil
+}
+
+// seed sets the stream's state from a 64-bit seed.
+func (s *Stream) seed(seed uint64) {
+	for i := range s.state {
+		s.state[i] = splitMix64(&seed)
+	}
+	s.origin = s.state
+}
+
+// next returns the next output and advances the state.
+func (s *Stream) next() uint64 {
+	x := s.nextRaw()
+	if s.antithetic {
+		x = ^x
+	}
+	if s.tap != nil {
+		x = s.tap(x)
+	}
+	return x
+}
+
+// nextRaw returns the next xoshiro256** output and advances the state.
+func (s *Stream) nextRaw() uint64 {
+	result := bits.RotateLeft64(s.state[1]*5, 7) * 9
+	t := s.state[1] << 17
+	s.state[2] ^= s.state[0]
+	s.state[3] ^= s.state[1]
+	s.state[1] ^= s.state[2]
+	s.state[0] ^= s.state[3]
+	s.state[2] ^= t
+	s.state[3] = bits.RotateLeft64(s.state[3], 45)
+	return result
+}
+
+// splitMix64 returns the next splitmix64 output for the provided state, which
+// is advanced.
+func splitMix64(x *uint64) uint64 {
+	*x += 0x9e3779b97f4a7c15
+	z := *x
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// toDelay rounds a value to a simulation delay.
+func toDelay(x float64) uint64 {
+	if x <= 0 || math.IsNaN(x) {
+		return 0
+	}
+	return uint64(math.Round(x))
+}
@@ -0,0 +1,107 @@
+package replay
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/juju/errgo"
+)
+
+// A Recorder logs a run as JSON Lines entries.  Set it as the Environment's
+// Observer to enable it, and call Flush() once the simulation is done.
+type Recorder struct {
+	env *simgo.Environment
+	w   *bufio.Writer
+	enc *json.Encoder
+	// eid is the ID of the event being processed
+	eid simgo.EventID
+	err error
+}
+
+var (
+	_ simgo.Observer = (*Recorder)(nil)
+	_ Session        = (*Recorder)(nil)
+)
+
+// NewRecorder returns a new Recorder that writes the log to w.
+func NewRecorder(env *simgo.Environment, w io.Writer) *Recorder {
+	bw := bufio.NewWriter(w)
+	return &Recorder{
+		env: env,
+		w:   bw,
+		enc: json.NewEncoder(bw),
+	}
+}
+
+// Flush writes any buffered entries and returns the first error encountered
+// while recording, if any.
+func (r *Recorder) Flush() error {
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+	return r.err
+}
+
+// Stream taps the provided stream so that its draws are logged.
+func (r *Recorder) Stream(s *random.Stream) *random.Stream {
+	s.Tap(func(x uint64) uint64 {
+		r.write(&Entry{Type: DrawEntry, Time: r.env.Now, EID: r.eid, Draw: x})
+		return x
+	})
+	return s
+}
+
+// Input logs the external input pointed to by v.
+func (r *Recorder) Input(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return errgo.Notef(err, "cannot record input %q", name)
+	}
+	r.write(&Entry{Type: InputEntry, Time: r.env.Now, EID: r.eid, Name: name, Input: data})
+	return nil
+}
+
+// EventScheduled logs calls to Environment.Run() with a time limit.
+func (r *Recorder) EventScheduled(event *simgo.Event, time uint64, priority int, eid simgo.EventID) {
+	if event.Kind() == simgo.KindStop {
+		r.write(&Entry{Type: RunEntry, Time: time, EID: eid})
+	}
+}
+
+// EventProcessed logs the processed event.
+func (r *Recorder) EventProcessed(event *simgo.Event, time uint64, priority int, eid simgo.EventID) {
+	r.eid = eid
+	r.write(&Entry{
+		Type:     EventEntry,
+		Time:     time,
+		EID:      eid,
+		Priority: priority,
+		Kind:     event.Kind(),
+		Name:     event.Name(),
+	})
+}
+
+// ProcessStarted does nothing.
+func (r *Recorder) ProcessStarted(p *simgo.Process) {}
+
+// ProcessResumed does nothing.
+func (r *Recorder) ProcessResumed(p *simgo.Process, event *simgo.Event) {}
+
+// ProcessSuspended does nothing.
+func (r *Recorder) ProcessSuspended(p *simgo.Process, event *simgo.Event) {}
+
+// ProcessFinished does nothing.
+func (r *Recorder) ProcessFinished(p *simgo.Process) {}
+
+// ConditionTriggered does nothing.
+func (r *Recorder) ConditionTriggered(c *simgo.Condition, event *simgo.Event) {}
+
+// write writes an entry unless writing already failed.
+func (r *Recorder) write(entry *Entry) {
+	if r.err == nil {
+		r.err = r.enc.Encode(entry)
+	}
+}
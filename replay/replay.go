@@ -0,0 +1,128 @@
+// Package replay records simgo simulation runs and replays them exactly.
+//
+// A Recorder logs every processed event, every number drawn from the random
+// streams it taps and every external input of a run.  A Replayer feeds the
+// recorded draws and inputs back into a new run of the same model and
+// verifies that the same events are processed in the same order, stopping at
+// the first divergence:
+//
+//	env := simgo.NewEnvironment()
+//	rec := replay.NewRecorder(env, f)
+//	env.Observer = rec
+//	rng := rec.Stream(random.New(seed))
+//	buildModel(env, rng, rec)
+//	env.Run(nil)
+//	rec.Flush()
+//
+//	env = simgo.NewEnvironment()
+//	rp, err := replay.NewReplayer(env, f)
+//	env.Observer = rp
+//	rng = rp.Stream(random.New(seed))
+//	buildModel(env, rng, rp)
+//	err = rp.Run()
+//
+// Models that are recorded and replayed take a Session, which is implemented
+// by both.
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+)
+
+// A Session is a recording or a replay of a run.
+type Session interface {
+	// Stream taps the provided stream, and the streams derived from it
+	// afterwards, and returns it.
+	Stream(s *random.Stream) *random.Stream
+	// Input records the external input pointed to by v when recording,
+	// and replaces it with the recorded input when replaying.
+	Input(name string, v interface{}) error
+}
+
+// EntryType is the type of a log entry.
+type EntryType string
+
+const (
+	// EventEntry is a processed event.
+	EventEntry EntryType = "event"
+	// DrawEntry is a number drawn from a random stream.
+	DrawEntry EntryType = "draw"
+	// InputEntry is an external input.
+	InputEntry EntryType = "input"
+	// RunEntry is a call to Environment.Run() with a time limit, which
+	// schedules a stop event.
+	RunEntry EntryType = "run"
+)
+
+// An Entry is a step of a recorded run.  Draws and inputs are logged with the
+// time and ID of the event being processed when they happen (0 before the
+// first event).  Runs are logged with their time limit and the ID of their
+// stop event.
+type Entry struct {
+	Type     EntryType       `json:"type"`
+	Time     uint64          `json:"time"`
+	EID      simgo.EventID   `json:"eid"`
+	Priority int             `json:"priority,omitempty"`
+	Kind     simgo.EventKind `json:"kind,omitempty"`
+	Name     string          `json:"name,omitempty"`
+	Draw     uint64          `json:"draw,omitempty"`
+	Input    json.RawMessage `json:"input,omitempty"`
+}
+
+// String returns a description of the entry.
+func (e *Entry) String() string {
+	switch e.Type {
+	case EventEntry:
+		return fmt.Sprintf("event %s %q at %d (priority %d, eid %d)", e.Kind, e.Name, e.Time, e.Priority, e.EID)
+	case DrawEntry:
+		return fmt.Sprintf("draw %d at %d (eid %d)", e.Draw, e.Time, e.EID)
+	case RunEntry:
+		return fmt.Sprintf("run until %d (eid %d)", e.Time, e.EID)
+	default:
+		return fmt.Sprintf("input %q = %s at %d (eid %d)", e.Name, e.Input, e.Time, e.EID)
+	}
+}
+
+// matches returns whether the actual entry matches the recorded one.  Draws
+// and inputs match if they happen at the same point of the run, since their
+// recorded values are fed back.
+func (e *Entry) matches(actual *Entry) bool {
+	if e.Type != actual.Type || e.Time != actual.Time || e.EID != actual.EID {
+		return false
+	}
+	switch e.Type {
+	case EventEntry:
+		return e.Priority == actual.Priority && e.Kind == actual.Kind && e.Name == actual.Name
+	case InputEntry:
+		return e.Name == actual.Name
+	default:
+		return true
+	}
+}
+
+// A Divergence is the first step at which a replay differs from the
+// recording.
+type Divergence struct {
+	// Index is the index of the entry in the recording
+	Index int
+	// Expected is the recorded entry, or nil if the recording ended
+	Expected *Entry
+	// Actual is what happened instead, or nil if the replay ended
+	Actual *Entry
+}
+
+// Error returns a diff of the expected and actual entries.
+func (d *Divergence) Error() string {
+	expected, actual := "end of recording", "end of run"
+	if d.Expected != nil {
+		expected = d.Expected.String()
+	}
+	if d.Actual != nil {
+		actual = d.Actual.String()
+	}
+	return fmt.Sprintf("replay diverged at entry %d:\n- %s\n+ %s", d.Index, expected, actual)
+}
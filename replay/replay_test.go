@@ -0,0 +1,109 @@
+package replay
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+)
+
+// buildModel builds a model whose customers arrive at random times.  The
+// number of customers is an external input, and extra adds a timeout to
+// simulate a change to the model.
+func buildModel(env *simgo.Environment, rng *random.Stream, s Session, extra bool) (*[]uint64, error) {
+	customers := 5
+	if err := s.Input("customers", &customers); err != nil {
+		return nil, err
+	}
+	var arrivals []uint64
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		for i := 0; i < customers; i++ {
+			to := simgo.NewTimeout(env, rng.Delay(random.Exponential(10)), nil, simgo.WithName("arrival"))
+			to.Schedule(env)
+			pc.Yield(to.Event)
+			arrivals = append(arrivals, env.Now)
+			if extra && i == 2 {
+				to := simgo.NewTimeout(env, 1, nil)
+				to.Schedule(env)
+				pc.Yield(to.Event)
+			}
+		}
+		return nil
+	}, simgo.WithName("source")))
+	p.Init()
+	return &arrivals, nil
+}
+
+// record records a run of the model and returns the log and arrivals.
+func record(t *testing.T, seed uint64) ([]byte, []uint64) {
+	var buf bytes.Buffer
+	env := simgo.NewEnvironment()
+	rec := NewRecorder(env, &buf)
+	env.Observer = rec
+	arrivals, err := buildModel(env, rec.Stream(random.New(seed)), rec, false)
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	env.Run(20)
+	env.Run(nil)
+	if err := rec.Flush(); err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	return buf.Bytes(), *arrivals
+}
+
+// replay replays the log with the model and returns the arrivals and the
+// replay error.
+func replay(t *testing.T, log []byte, seed uint64, extra bool) ([]uint64, error) {
+	env := simgo.NewEnvironment()
+	rp, err := NewReplayer(env, bytes.NewReader(log))
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	env.Observer = rp
+	arrivals, err := buildModel(env, rp.Stream(random.New(seed)), rp, extra)
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	err = rp.Run()
+	return *arrivals, err
+}
+
+func TestReplay(t *testing.T) {
+	log, want := record(t, 1)
+	// The recorded draws are fed back, so the seed does not matter.
+	got, err := replay(t, log, 2, false)
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("arrivals = %v, want: %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("arrivals = %v, want: %v", got, want)
+		}
+	}
+}
+
+func TestReplayDivergence(t *testing.T) {
+	log, _ := record(t, 1)
+	_, err := replay(t, log, 1, true)
+	d, ok := err.(*Divergence)
+	if !ok {
+		t.Fatalf("err = %v, want: a *Divergence", err)
+	}
+	if d.Expected == nil || d.Actual == nil {
+		t.Fatalf("d = %+v, want: expected and actual entries", d)
+	}
+	// After the third arrival, the model draws the next delay instead of
+	// waiting for the extra timeout.
+	if d.Expected.Type != DrawEntry || d.Actual.Type != EventEntry || d.Actual.Kind != simgo.KindTimeout {
+		t.Errorf("err = %s, want: a draw replaced by a timeout", d)
+	}
+	if !strings.HasPrefix(d.Error(), "replay diverged at entry") {
+		t.Errorf("d.Error() = %q, want: a diff", d.Error())
+	}
+}
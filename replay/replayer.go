@@ -0,0 +1,159 @@
+package replay
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/juju/errgo"
+)
+
+// A Replayer replays a log written by a Recorder.  Set it as the
+// Environment's Observer, build the model with the Replayer as its Session
+// and call Run() instead of running the Environment.
+type Replayer struct {
+	env     *simgo.Environment
+	entries []Entry
+	// next is the index of the next expected entry
+	next int
+	// eid is the ID of the event being processed
+	eid simgo.EventID
+	// processed is whether an event was processed by the last step
+	processed  bool
+	divergence *Divergence
+}
+
+var (
+	_ simgo.Observer = (*Replayer)(nil)
+	_ Session        = (*Replayer)(nil)
+)
+
+// NewReplayer returns a new Replayer for the log read from r.
+func NewReplayer(env *simgo.Environment, r io.Reader) (*Replayer, error) {
+	rp := &Replayer{env: env}
+	dec := json.NewDecoder(r)
+	for {
+		var entry Entry
+		err := dec.Decode(&entry)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errgo.Notef(err, "cannot decode entry %d", len(rp.entries))
+		}
+		rp.entries = append(rp.entries, entry)
+	}
+	return rp, nil
+}
+
+// Run steps the Environment until the whole log has been replayed.  It
+// returns a *Divergence error as soon as the replay differs from the log:
+// when an event is processed with a different time, priority or ID (or kind
+// or name), when a draw or input happens at a different point of the run, or
+// when the run ends early.
+//
+// The recorded run may be made of several calls to Environment.Run() with a
+// time limit.  Each of them is replayed by running the Environment until the
+// same time, which processes the remaining events up to that time even after
+// a divergence.
+func (rp *Replayer) Run() error {
+	for rp.divergence == nil && rp.next < len(rp.entries) {
+		rp.processed = false
+		if expected := &rp.entries[rp.next]; expected.Type == RunEntry {
+			rp.env.Run(expected.Time)
+		} else {
+			rp.env.Step()
+		}
+		if !rp.processed && rp.divergence == nil {
+			rp.divergence = &Divergence{Index: rp.next, Expected: &rp.entries[rp.next]}
+		}
+	}
+	if rp.divergence != nil {
+		return rp.divergence
+	}
+	return nil
+}
+
+// Stream taps the provided stream so that the recorded draws are used
+// instead of its own.
+func (rp *Replayer) Stream(s *random.Stream) *random.Stream {
+	s.Tap(func(x uint64) uint64 {
+		if expected := rp.expect(&Entry{Type: DrawEntry, Time: rp.env.Now, EID: rp.eid, Draw: x}); expected != nil {
+			return expected.Draw
+		}
+		return x
+	})
+	return s
+}
+
+// Input replaces the external input pointed to by v with the recorded one.
+// It returns a *Divergence error if no input with the provided name was
+// recorded at this point of the run.
+func (rp *Replayer) Input(name string, v interface{}) error {
+	actual := &Entry{Type: InputEntry, Time: rp.env.Now, EID: rp.eid, Name: name}
+	actual.Input, _ = json.Marshal(v)
+	expected := rp.expect(actual)
+	if expected == nil {
+		return rp.divergence
+	}
+	if err := json.Unmarshal(expected.Input, v); err != nil {
+		return errgo.Notef(err, "cannot replay input %q", name)
+	}
+	return nil
+}
+
+// EventScheduled checks calls to Environment.Run() with a time limit against
+// the log.
+func (rp *Replayer) EventScheduled(event *simgo.Event, time uint64, priority int, eid simgo.EventID) {
+	if event.Kind() == simgo.KindStop {
+		rp.expect(&Entry{Type: RunEntry, Time: time, EID: eid})
+	}
+}
+
+// EventProcessed checks the processed event against the log.
+func (rp *Replayer) EventProcessed(event *simgo.Event, time uint64, priority int, eid simgo.EventID) {
+	rp.processed = true
+	rp.eid = eid
+	rp.expect(&Entry{
+		Type:     EventEntry,
+		Time:     time,
+		EID:      eid,
+		Priority: priority,
+		Kind:     event.Kind(),
+		Name:     event.Name(),
+	})
+}
+
+// ProcessStarted does nothing.
+func (rp *Replayer) ProcessStarted(p *simgo.Process) {}
+
+// ProcessResumed does nothing.
+func (rp *Replayer) ProcessResumed(p *simgo.Process, event *simgo.Event) {}
+
+// ProcessSuspended does nothing.
+func (rp *Replayer) ProcessSuspended(p *simgo.Process, event *simgo.Event) {}
+
+// ProcessFinished does nothing.
+func (rp *Replayer) ProcessFinished(p *simgo.Process) {}
+
+// ConditionTriggered does nothing.
+func (rp *Replayer) ConditionTriggered(c *simgo.Condition, event *simgo.Event) {}
+
+// expect returns the next entry of the log and moves past it if it matches
+// the actual entry.  Otherwise it records the divergence and returns nil.
+func (rp *Replayer) expect(actual *Entry) *Entry {
+	if rp.divergence != nil {
+		return nil
+	}
+	if rp.next >= len(rp.entries) || !rp.entries[rp.next].matches(actual) {
+		rp.divergence = &Divergence{Index: rp.next, Actual: actual}
+		if rp.next < len(rp.entries) {
+			rp.divergence.Expected = &rp.entries[rp.next]
+		}
+		return nil
+	}
+	expected := &rp.entries[rp.next]
+	rp.next++
+	return expected
+}
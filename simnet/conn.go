@@ -0,0 +1,550 @@
+package simnet
+
+import (
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/clock"
+	"github.com/juju/errgo"
+)
+
+// headerSize is the size in bytes of the header of a segment.
+const headerSize = 40
+
+// firstEphemeralPort is the first port assigned to dialing connections.
+const firstEphemeralPort = 49152
+
+// ConnConfig configures how connections deliver their segments reliably over
+// the links of a network.
+type ConnConfig struct {
+	// RetransmitTimeout is the simulation time after which a segment that
+	// has not been acknowledged is sent again.  It doubles with each
+	// retransmission of the segment.
+	RetransmitTimeout uint64
+	// MaxRetransmits is the number of times a segment is sent again before
+	// the connection is broken
+	MaxRetransmits int
+	// SegmentSize is the maximum number of data bytes in a segment
+	SegmentSize int
+}
+
+// defaultConnConfig is the configuration of the connections of a new Network.
+var defaultConnConfig = ConnConfig{
+	RetransmitTimeout: 200,
+	MaxRetransmits:    8,
+	SegmentSize:       1460,
+}
+
+// SetConnConfig configures the connections of the network.  The default
+// configuration retransmits after 200 units of simulation time, up to 8
+// times, and sends up to 1460 bytes per segment.  A RetransmitTimeout or
+// SegmentSize that is not positive takes its default value.
+func (n *Network) SetConnConfig(cfg ConnConfig) {
+	if cfg.RetransmitTimeout == 0 {
+		cfg.RetransmitTimeout = defaultConnConfig.RetransmitTimeout
+	}
+	if cfg.SegmentSize <= 0 {
+		cfg.SegmentSize = defaultConnConfig.SegmentSize
+	}
+	n.connCfg = cfg
+}
+
+// SetClock sets the clock that converts the deadlines of connections to
+// simulation time.  By default, simulation time 0 is the Unix epoch and one
+// unit is a millisecond.
+func (n *Network) SetClock(c *clock.Sim) {
+	n.clock = c
+}
+
+// Clock returns the clock that converts the deadlines of connections to
+// simulation time.
+func (n *Network) Clock() *clock.Sim {
+	return n.clock
+}
+
+// An Addr is the address of an endpoint of a connection.
+type Addr struct {
+	// Node is the name of the node
+	Node string
+	// Port is the port on the node
+	Port int
+}
+
+var _ net.Addr = Addr{}
+
+// Network returns "simnet".
+func (a Addr) Network() string {
+	return "simnet"
+}
+
+func (a Addr) String() string {
+	return net.JoinHostPort(a.Node, strconv.Itoa(a.Port))
+}
+
+// segmentKind is the kind of a segment.
+type segmentKind int
+
+const (
+	synSegment segmentKind = iota
+	synAckSegment
+	dataSegment
+	finSegment
+	ackSegment
+	rstSegment
+)
+
+// A segment is the payload of the messages sent by connections.  Segments
+// other than acks and resets are delivered reliably and in order: they are
+// numbered, acknowledged, and sent again until they are.
+type segment struct {
+	kind     segmentKind
+	src, dst Addr
+	// seq is the sequence number of the segment, or the sequence number of
+	// the next segment expected for an ack
+	seq  uint64
+	data []byte
+}
+
+// Listen returns a Listener for connections to the provided port of the node.
+// A port of 0 picks an unused port.
+func (node *Node) Listen(port int) (*Listener, error) {
+	s := node.getStack()
+	if port == 0 {
+		port = s.ephemeralPort()
+	}
+	if _, ok := s.listeners[port]; ok {
+		return nil, errgo.Newf("port %d of node %q is in use", port, node.Name)
+	}
+	l := &Listener{node: node, addr: Addr{node.Name, port}}
+	s.listeners[port] = l
+	return l, nil
+}
+
+// Dial connects to the address ("node:port") and returns the connection
+// once it is established.  It must be called from a simgo process, which is
+// suspended while the connection is established.
+func (node *Node) Dial(address string) (net.Conn, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, errgo.Mask(err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, errgo.Notef(err, "invalid port in %q", address)
+	}
+	if _, ok := node.net.links[linkKey{node.Name, host}]; !ok {
+		return nil, errgo.Newf("no link from %q to %q", node.Name, host)
+	}
+	s := node.getStack()
+	c := s.newConn(Addr{node.Name, s.ephemeralPort()}, Addr{host, port})
+	c.sendReliable(synSegment, nil)
+	for !c.established && c.err == nil {
+		c.waiters.wait(node.net, "Dial", 0)
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return c, nil
+}
+
+// stack holds the listeners and connections of a node.
+type stack struct {
+	node      *Node
+	listeners map[int]*Listener
+	conns     map[connKey]*Conn
+	// nextPort is the next ephemeral port
+	nextPort int
+}
+
+// connKey identifies a connection of a node by its local port and remote
+// address.
+type connKey struct {
+	port   int
+	remote Addr
+}
+
+// getStack returns the stack of the node, creating it if needed.
+func (node *Node) getStack() *stack {
+	if node.stack == nil {
+		node.stack = &stack{
+			node:      node,
+			listeners: make(map[int]*Listener),
+			conns:     make(map[connKey]*Conn),
+			nextPort:  firstEphemeralPort,
+		}
+	}
+	return node.stack
+}
+
+// ephemeralPort returns an unused port.
+func (s *stack) ephemeralPort() int {
+	for {
+		port := s.nextPort
+		s.nextPort++
+		if _, ok := s.listeners[port]; !ok {
+			return port
+		}
+	}
+}
+
+// newConn registers and returns a new connection.
+func (s *stack) newConn(local, remote Addr) *Conn {
+	c := &Conn{
+		node:    s.node,
+		local:   local,
+		remote:  remote,
+		unacked: make(map[uint64]*segment),
+		early:   make(map[uint64]*segment),
+	}
+	s.conns[connKey{local.Port, remote}] = c
+	return c
+}
+
+// removeConn unregisters a connection, so that the segments sent to it are
+// answered with resets.
+func (s *stack) removeConn(c *Conn) {
+	key := connKey{c.local.Port, c.remote}
+	if s.conns[key] == c {
+		delete(s.conns, key)
+	}
+}
+
+// handle handles a segment delivered to the node.
+func (s *stack) handle(seg *segment) {
+	if c, ok := s.conns[connKey{seg.dst.Port, seg.src}]; ok {
+		c.receive(seg)
+		return
+	}
+	switch seg.kind {
+	case synSegment:
+		l, ok := s.listeners[seg.dst.Port]
+		if !ok {
+			break
+		}
+		c := s.newConn(seg.dst, seg.src)
+		c.established = true
+		c.receive(seg)
+		c.sendReliable(synAckSegment, nil)
+		l.pending = append(l.pending, c)
+		l.waiters.wake()
+		return
+	case rstSegment:
+		return
+	}
+	s.node.sendSegment(&segment{kind: rstSegment, src: seg.dst, dst: seg.src})
+}
+
+// sendSegment sends a segment over the network.  Segments that cannot be
+// sent are dropped, like lost ones.
+func (node *Node) sendSegment(seg *segment) {
+	node.net.send(seg.src.Node, seg.dst.Node, seg, headerSize+len(seg.data))
+}
+
+// A Listener is a net.Listener for the connections to a port of a node.
+type Listener struct {
+	node    *Node
+	addr    Addr
+	pending []*Conn
+	waiters waitQueue
+	closed  bool
+}
+
+var _ net.Listener = (*Listener)(nil)
+
+// Accept returns the next connection to the listener.  It must be called
+// from a simgo process, which is suspended until there is a connection.
+func (l *Listener) Accept() (net.Conn, error) {
+	for {
+		if l.closed {
+			return nil, net.ErrClosed
+		}
+		if len(l.pending) > 0 {
+			c := l.pending[0]
+			l.pending = l.pending[1:]
+			return c, nil
+		}
+		l.waiters.wait(l.node.net, "Accept", 0)
+	}
+}
+
+// Close stops listening.  Pending calls to Accept() return net.ErrClosed.
+func (l *Listener) Close() error {
+	if l.closed {
+		return net.ErrClosed
+	}
+	l.closed = true
+	delete(l.node.getStack().listeners, l.addr.Port)
+	l.waiters.wake()
+	return nil
+}
+
+// Addr returns the address of the listener.
+func (l *Listener) Addr() net.Addr {
+	return l.addr
+}
+
+// A Conn is a net.Conn whose bytes travel over the links of a network.
+//
+// Data is split into segments that are delivered reliably and in order:
+// segments that are lost, or dropped by a partition, are sent again with
+// exponential backoff until they are acknowledged, and the connection breaks
+// once a segment has been sent again MaxRetransmits times (see ConnConfig).
+//
+// Read() must be called from a simgo process, which is suspended (in
+// simulation time) until there is data.  Write() never blocks: the data is
+// sent right away, and the link delays it according to its bandwidth and
+// latency.  Deadlines are converted to simulation time by the clock of the
+// network (see SetClock()).  Events cannot be removed from the Environment,
+// so the timeouts of acknowledged segments and of deadlines are still
+// processed (and ignored).
+//
+// Code that uses a Conn must run in simgo processes: like the channels of
+// clock.Sim, a Conn cannot be used from other goroutines while the
+// simulation runs.  In particular, protocol code that starts goroutines for
+// reading (e.g., net/rpc) cannot be used, whereas code that reads and writes
+// in the calling goroutine (e.g., net/textproto or encoding/gob) can.
+type Conn struct {
+	node          *Node
+	local, remote Addr
+	// sendSeq is the sequence number of the next reliable segment sent
+	sendSeq uint64
+	// unacked holds the reliable segments sent that have not been
+	// acknowledged, by sequence number
+	unacked map[uint64]*segment
+	// recvNext is the sequence number of the next reliable segment expected
+	recvNext uint64
+	// early holds the reliable segments received ahead of recvNext
+	early map[uint64]*segment
+	// buf holds the data received that has not been read
+	buf []byte
+	// established is whether the handshake has completed, and eof is
+	// whether the peer has closed the connection
+	established, eof bool
+	closed           bool
+	// err is the error that broke the connection, if any
+	err                         error
+	readDeadline, writeDeadline time.Time
+	waiters                     waitQueue
+}
+
+var _ net.Conn = (*Conn)(nil)
+
+// Read reads data from the connection, suspending the active process until
+// there is some.  It returns io.EOF once the peer has closed the connection
+// and all data has been read.
+func (c *Conn) Read(b []byte) (int, error) {
+	for {
+		switch {
+		case c.closed:
+			return 0, net.ErrClosed
+		case len(c.buf) > 0:
+			n := copy(b, c.buf)
+			c.buf = c.buf[n:]
+			return n, nil
+		case c.err != nil:
+			return 0, c.err
+		case c.eof:
+			return 0, io.EOF
+		}
+		deadline, ok := c.deadline(c.readDeadline)
+		if !ok {
+			return 0, os.ErrDeadlineExceeded
+		}
+		c.waiters.wait(c.node.net, "Read", deadline)
+	}
+}
+
+// Write sends data over the connection.
+func (c *Conn) Write(b []byte) (int, error) {
+	switch {
+	case c.closed:
+		return 0, net.ErrClosed
+	case c.err != nil:
+		return 0, c.err
+	}
+	if _, ok := c.deadline(c.writeDeadline); !ok {
+		return 0, os.ErrDeadlineExceeded
+	}
+	size := c.node.net.connCfg.SegmentSize
+	for i := 0; i < len(b); i += size {
+		end := i + size
+		if end > len(b) {
+			end = len(b)
+		}
+		c.sendReliable(dataSegment, append([]byte(nil), b[i:end]...))
+	}
+	return len(b), nil
+}
+
+// Close closes the connection.  The peer reads io.EOF once it has read the
+// data sent before.  The connection is removed from its node once the peer
+// has acknowledged all of it, and the segments that the peer sends after
+// that are answered with resets.
+func (c *Conn) Close() error {
+	if c.closed {
+		return net.ErrClosed
+	}
+	c.closed = true
+	if c.err == nil {
+		c.sendReliable(finSegment, nil)
+	}
+	c.waiters.wake()
+	return nil
+}
+
+// LocalAddr returns the local address of the connection.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.local
+}
+
+// RemoteAddr returns the address of the peer.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// SetDeadline sets the read and write deadlines.
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.readDeadline = t
+	c.writeDeadline = t
+	c.waiters.wake()
+	return nil
+}
+
+// SetReadDeadline sets the read deadline.  A zero value means no deadline.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.readDeadline = t
+	c.waiters.wake()
+	return nil
+}
+
+// SetWriteDeadline sets the write deadline.  A zero value means no deadline.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline = t
+	return nil
+}
+
+// deadline returns the simulation time of the deadline t (0 for none), and
+// false if it has passed.
+func (c *Conn) deadline(t time.Time) (uint64, bool) {
+	if t.IsZero() {
+		return 0, true
+	}
+	at := c.node.net.clock.At(t)
+	return at, at > c.node.net.env.Now
+}
+
+// sendReliable sends a segment that is delivered reliably.
+func (c *Conn) sendReliable(kind segmentKind, data []byte) {
+	seg := &segment{kind: kind, src: c.local, dst: c.remote, seq: c.sendSeq, data: data}
+	c.sendSeq++
+	c.unacked[seg.seq] = seg
+	c.transmit(seg, 0)
+}
+
+// transmit sends a reliable segment and schedules its retransmission.
+func (c *Conn) transmit(seg *segment, attempt int) {
+	c.node.sendSegment(seg)
+	n := c.node.net
+	timeout := n.connCfg.RetransmitTimeout << uint(attempt)
+	n.at(n.env.Now+timeout, "retransmit", func() {
+		if c.err != nil || c.unacked[seg.seq] != seg {
+			return
+		}
+		if attempt >= n.connCfg.MaxRetransmits {
+			c.fail(errgo.Newf("connection to %s timed out", c.remote))
+			return
+		}
+		c.transmit(seg, attempt+1)
+	})
+}
+
+// receive handles a segment of the connection.
+func (c *Conn) receive(seg *segment) {
+	switch seg.kind {
+	case ackSegment:
+		for seq := range c.unacked {
+			if seq < seg.seq {
+				delete(c.unacked, seq)
+			}
+		}
+		if c.closed && len(c.unacked) == 0 {
+			c.node.getStack().removeConn(c)
+		}
+		return
+	case rstSegment:
+		if c.established {
+			c.fail(errgo.Newf("connection reset by %s", c.remote))
+		} else {
+			c.fail(errgo.Newf("connection refused by %s", c.remote))
+		}
+		return
+	}
+	if seg.seq >= c.recvNext {
+		c.early[seg.seq] = seg
+	}
+	for {
+		next, ok := c.early[c.recvNext]
+		if !ok {
+			break
+		}
+		delete(c.early, c.recvNext)
+		c.recvNext++
+		switch next.kind {
+		case synAckSegment:
+			c.established = true
+		case dataSegment:
+			c.buf = append(c.buf, next.data...)
+		case finSegment:
+			c.eof = true
+		}
+	}
+	// Duplicates are acknowledged too, in case the previous ack was lost.
+	c.node.sendSegment(&segment{kind: ackSegment, src: c.local, dst: c.remote, seq: c.recvNext})
+	c.waiters.wake()
+}
+
+// fail breaks the connection with the provided error and removes it from
+// its node.
+func (c *Conn) fail(err error) {
+	c.err = err
+	c.unacked = make(map[uint64]*segment)
+	c.node.getStack().removeConn(c)
+	c.waiters.wake()
+}
+
+// A waitQueue holds the events that processes wait on for a change of state.
+type waitQueue []*simgo.Event
+
+// wait suspends the active process until wake() is called, or until the
+// simulation time deadline if it is not 0.  It panics if it is not called
+// from a process.
+func (q *waitQueue) wait(n *Network, caller string, deadline uint64) {
+	p := n.env.ActiveProcess
+	if p == nil {
+		panic("simnet: " + caller + " must be called from a simgo process")
+	}
+	e := simgo.NewEvent(n.env, simgo.WithName("simnet."+caller))
+	*q = append(*q, e)
+	if deadline != 0 {
+		n.at(deadline, "deadline", func() {
+			if !e.Triggered() {
+				e.Succeed(nil)
+			}
+		})
+	}
+	p.ProcComm().Yield(e)
+}
+
+// wake resumes the processes waiting on the queue.
+func (q *waitQueue) wake() {
+	for _, e := range *q {
+		if !e.Triggered() {
+			e.Succeed(nil)
+		}
+	}
+	*q = nil
+}
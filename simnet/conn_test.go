@@ -0,0 +1,296 @@
+package simnet
+
+import (
+	"encoding/gob"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+)
+
+// spawn starts a process running fn.
+func spawn(env *simgo.Environment, name string, fn func()) {
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		fn()
+		return nil
+	}, simgo.WithName(name)))
+	p.Init()
+}
+
+// serveUpper starts a textproto server on the node that answers each line
+// with the line in upper case.
+func serveUpper(t *testing.T, env *simgo.Environment, node *Node, port int) {
+	l, err := node.Listen(port)
+	if err != nil {
+		t.Fatalf("Listen() = %v, want: nil", err)
+	}
+	spawn(env, "accept", func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			spawn(env, "serve", func() {
+				tc := textproto.NewConn(conn)
+				defer tc.Close()
+				for {
+					line, err := tc.ReadLine()
+					if err != nil {
+						return
+					}
+					tc.PrintfLine("%s", strings.ToUpper(line))
+				}
+			})
+		}
+	})
+}
+
+func TestConn(t *testing.T) {
+	env := simgo.NewEnvironment()
+	network := New(env, random.New(1))
+	network.Connect("client", "server", LinkConfig{Latency: random.Constant(10)})
+	serveUpper(t, env, network.Node("server"), 80)
+	var replies []string
+	var times []uint64
+	spawn(env, "client", func() {
+		conn, err := network.Node("client").Dial("server:80")
+		if err != nil {
+			t.Errorf("Dial() = %v, want: nil", err)
+			return
+		}
+		times = append(times, env.Now)
+		if got := conn.RemoteAddr().String(); got != "server:80" {
+			t.Errorf("RemoteAddr() = %s, want: server:80", got)
+		}
+		tc := textproto.NewConn(conn)
+		for _, line := range []string{"spam", "eggs"} {
+			tc.PrintfLine("%s", line)
+			reply, err := tc.ReadLine()
+			if err != nil {
+				t.Errorf("ReadLine() = %v, want: nil", err)
+				return
+			}
+			replies = append(replies, reply)
+			times = append(times, env.Now)
+		}
+		tc.Close()
+	})
+	env.Run(nil)
+	if strings.Join(replies, ",") != "SPAM,EGGS" {
+		t.Errorf("replies = %v, want: [SPAM EGGS]", replies)
+	}
+	// The handshake takes a round trip, and so does each request.
+	want := []uint64{20, 40, 60}
+	if len(times) != 3 || times[0] != want[0] || times[1] != want[1] || times[2] != want[2] {
+		t.Errorf("times = %v, want: %v", times, want)
+	}
+}
+
+func TestConnLoss(t *testing.T) {
+	env := simgo.NewEnvironment()
+	network := New(env, random.New(1))
+	network.Connect("client", "server", LinkConfig{
+		Latency:   random.Uniform(5, 50),
+		Bandwidth: 1000,
+		Loss:      0.2,
+		Duplicate: 0.1,
+		Reorder:   0.2,
+	})
+	l, err := network.Node("server").Listen(0)
+	if err != nil {
+		t.Fatalf("Listen() = %v, want: nil", err)
+	}
+	var received []byte
+	spawn(env, "server", func() {
+		conn, err := l.Accept()
+		if err != nil {
+			t.Errorf("Accept() = %v, want: nil", err)
+			return
+		}
+		received, err = io.ReadAll(conn)
+		if err != nil {
+			t.Errorf("ReadAll() = %v, want: nil", err)
+		}
+		conn.Close()
+		l.Close()
+	})
+	sent := make([]byte, 20000)
+	for i := range sent {
+		sent[i] = byte(i)
+	}
+	spawn(env, "client", func() {
+		conn, err := network.Node("client").Dial(l.Addr().String())
+		if err != nil {
+			t.Errorf("Dial() = %v, want: nil", err)
+			return
+		}
+		conn.Write(sent)
+		conn.Close()
+	})
+	env.Run(nil)
+	if string(received) != string(sent) {
+		t.Errorf("received %d bytes, want: the %d bytes sent, in order", len(received), len(sent))
+	}
+	if s := network.Stats("client", "server"); s.Lost == 0 {
+		t.Errorf("Lost = 0, want: some segments to be retransmitted")
+	}
+}
+
+func TestConnRefused(t *testing.T) {
+	env := simgo.NewEnvironment()
+	network := New(env, random.New(1))
+	network.Connect("client", "server", LinkConfig{Latency: random.Constant(10)})
+	var refusedAt uint64
+	spawn(env, "client", func() {
+		if _, err := network.Node("client").Dial("server:80"); err == nil || !strings.Contains(err.Error(), "refused") {
+			t.Errorf("Dial() = %v, want: connection refused", err)
+		}
+		refusedAt = env.Now
+		if _, err := network.Node("client").Dial("elsewhere:80"); err == nil {
+			t.Errorf("Dial() = nil, want: an error without a link")
+		}
+	})
+	env.Run(nil)
+	if refusedAt != 20 {
+		t.Errorf("refused at %d, want: 20", refusedAt)
+	}
+}
+
+func TestConnPartition(t *testing.T) {
+	env := simgo.NewEnvironment()
+	network := New(env, random.New(1))
+	network.Connect("client", "server", LinkConfig{Latency: random.Constant(10)})
+	serveUpper(t, env, network.Node("server"), 80)
+	network.Partition(30, 1000, []string{"client"}, []string{"server"})
+	var timeoutAt, replyAt uint64
+	spawn(env, "client", func() {
+		conn, err := network.Node("client").Dial("server:80")
+		if err != nil {
+			t.Errorf("Dial() = %v, want: nil", err)
+			return
+		}
+		to := simgo.NewTimeout(env, 10, nil)
+		to.Schedule(env)
+		env.ActiveProcess.ProcComm().Yield(to.Event)
+		tc := textproto.NewConn(conn)
+		tc.PrintfLine("spam")
+		conn.SetReadDeadline(network.Clock().Now().Add(500 * time.Millisecond))
+		if _, err := tc.ReadLine(); !isTimeout(err) {
+			t.Errorf("ReadLine() = %v, want: a timeout", err)
+		}
+		timeoutAt = env.Now
+		conn.SetReadDeadline(time.Time{})
+		tc = textproto.NewConn(conn)
+		if reply, err := tc.ReadLine(); reply != "SPAM" || err != nil {
+			t.Errorf("ReadLine() = %q, %v, want: SPAM, nil", reply, err)
+		}
+		replyAt = env.Now
+	})
+	env.Run(uint64(10000))
+	if timeoutAt != 530 {
+		t.Errorf("timeout at %d, want: 530", timeoutAt)
+	}
+	// The request is sent at 30, 230, 630 and 1430, once the partition
+	// has healed.
+	if replyAt != 1450 {
+		t.Errorf("reply at %d, want: 1450", replyAt)
+	}
+}
+
+func TestConnTimedOut(t *testing.T) {
+	env := simgo.NewEnvironment()
+	network := New(env, random.New(1))
+	network.SetConnConfig(ConnConfig{RetransmitTimeout: 10, MaxRetransmits: 2, SegmentSize: 100})
+	network.Connect("client", "server", LinkConfig{Latency: random.Constant(1)})
+	network.Partition(0, 0, []string{"client"}, []string{"server"})
+	var failedAt uint64
+	spawn(env, "client", func() {
+		_, err := network.Node("client").Dial("server:80")
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("Dial() = %v, want: connection timed out", err)
+		}
+		failedAt = env.Now
+	})
+	env.Run(nil)
+	// Sent at 0, 10 and 30, and given up at 70.
+	if failedAt != 70 {
+		t.Errorf("failed at %d, want: 70", failedAt)
+	}
+}
+
+func TestConnGob(t *testing.T) {
+	type request struct {
+		A, B int
+	}
+	type response struct {
+		Sum int
+	}
+	env := simgo.NewEnvironment()
+	network := New(env, random.New(1))
+	network.Connect("client", "server", LinkConfig{Latency: random.Constant(10), Loss: 0.3})
+	// A partial configuration takes the default segment size.
+	network.SetConnConfig(ConnConfig{RetransmitTimeout: 50, MaxRetransmits: 3})
+	l, err := network.Node("server").Listen(80)
+	if err != nil {
+		t.Fatalf("Listen() = %v, want: nil", err)
+	}
+	spawn(env, "server", func() {
+		conn, err := l.Accept()
+		if err != nil {
+			t.Errorf("Accept() = %v, want: nil", err)
+			return
+		}
+		defer conn.Close()
+		dec, enc := gob.NewDecoder(conn), gob.NewEncoder(conn)
+		for {
+			var req request
+			if err := dec.Decode(&req); err != nil {
+				return
+			}
+			enc.Encode(response{req.A + req.B})
+		}
+	})
+	var sums []int
+	spawn(env, "client", func() {
+		conn, err := network.Node("client").Dial("server:80")
+		if err != nil {
+			t.Errorf("Dial() = %v, want: nil", err)
+			return
+		}
+		defer conn.Close()
+		dec, enc := gob.NewDecoder(conn), gob.NewEncoder(conn)
+		for i := 1; i <= 3; i++ {
+			if err := enc.Encode(request{i, 10 * i}); err != nil {
+				t.Errorf("Encode() = %v, want: nil", err)
+				return
+			}
+			var resp response
+			if err := dec.Decode(&resp); err != nil {
+				t.Errorf("Decode() = %v, want: nil", err)
+				return
+			}
+			sums = append(sums, resp.Sum)
+		}
+	})
+	env.Run(nil)
+	if len(sums) != 3 || sums[0] != 11 || sums[1] != 22 || sums[2] != 33 {
+		t.Errorf("sums = %v, want: [11 22 33]", sums)
+	}
+	// Both ends are removed once closed.
+	for _, name := range []string{"client", "server"} {
+		if n := len(network.Node(name).getStack().conns); n != 0 {
+			t.Errorf("node %q has %d connections, want: 0", name, n)
+		}
+	}
+}
+
+// isTimeout returns whether err is a timeout.
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
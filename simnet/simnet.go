@@ -0,0 +1,390 @@
+// Package simnet simulates a message-passing network on top of simgo, for
+// testing distributed systems under simulated time.
+//
+// A Network connects named nodes with directed links.  Each link has a
+// latency distribution, a bandwidth that delays messages while they are
+// serialized onto the link, and probabilities of losing, duplicating and
+// reordering messages.  Partitions can be scripted to start and heal at
+// given simulation times.  Messages are delivered into the inbox (a
+// simgo.Store) of the destination node, so processes receive them by
+// yielding the event returned by Node.Recv():
+//
+//	net := simnet.New(env, rng)
+//	a, b := net.AddNode("a"), net.AddNode("b")
+//	net.Connect("a", "b", simnet.LinkConfig{Latency: random.Exponential(5)})
+//	a.Send("b", "ping", 64)
+//	// In a process of node b:
+//	msg := pc.Yield(b.Recv()).(*simnet.Message)
+//
+// Nodes can also talk over reliable byte streams: see Conn and Listener.
+//
+// All randomness comes from the stream provided to New(), so a network
+// behaves the same way in every run with the same seed.
+package simnet
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/clock"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/stats"
+	"github.com/juju/errgo"
+)
+
+// A Message is a message sent over the network.
+type Message struct {
+	// From and To are the names of the sending and receiving nodes
+	From, To string
+	// Payload is the content of the message
+	Payload interface{}
+	// Size is the size of the message in bytes
+	Size int
+	// Seq is the sequence number of the message on its link
+	Seq uint64
+	// Sent is the simulation time the message was sent at
+	Sent uint64
+	// Duplicate is whether the message is a duplicate made by the network
+	Duplicate bool
+}
+
+// LinkConfig configures a link.
+type LinkConfig struct {
+	// Latency is the distribution of the propagation delay.  Nil means no
+	// delay.
+	Latency random.Distribution
+	// Bandwidth is the number of bytes the link transmits per unit of
+	// simulation time.  Messages are sent one at a time, and each one
+	// occupies the link for Size/Bandwidth units.  0 means no limit.
+	Bandwidth float64
+	// Loss is the probability that a message is lost
+	Loss float64
+	// Duplicate is the probability that a message is delivered twice.
+	// The copy has its own latency.
+	Duplicate float64
+	// Reorder is the probability that a message may be overtaken by later
+	// messages: it is delayed by a second latency sample.  Other messages
+	// are delivered in the order they were sent.
+	Reorder float64
+}
+
+// LinkStats are the statistics of a link.
+type LinkStats struct {
+	// Sent is the number of messages sent on the link
+	Sent int
+	// Bytes is the number of bytes sent on the link
+	Bytes int
+	// Delivered is the number of messages delivered, including duplicates
+	Delivered int
+	// Lost is the number of messages lost
+	Lost int
+	// Duplicated is the number of messages duplicated
+	Duplicated int
+	// Reordered is the number of messages that could be overtaken
+	Reordered int
+	// Partitioned is the number of messages dropped by a partition
+	Partitioned int
+	// Latency is the mean time between sending and delivering messages
+	Latency float64
+	// MaxLatency is the maximum time between sending and delivering
+	// messages
+	MaxLatency float64
+}
+
+// A Network is a simulated network of nodes connected by links.
+type Network struct {
+	env   *simgo.Environment
+	rng   *random.Stream
+	nodes map[string]*Node
+	links map[linkKey]*link
+	// partitions holds the active partitions
+	partitions []*partition
+	// clock converts the deadlines of connections to simulation time
+	clock   *clock.Sim
+	connCfg ConnConfig
+}
+
+// New returns a new Network in the provided Environment, which draws its
+// random numbers from substreams of rng.
+func New(env *simgo.Environment, rng *random.Stream) *Network {
+	return &Network{
+		env:     env,
+		rng:     rng,
+		nodes:   make(map[string]*Node),
+		links:   make(map[linkKey]*link),
+		clock:   clock.NewSim(env, time.Unix(0, 0).UTC(), time.Millisecond),
+		connCfg: defaultConnConfig,
+	}
+}
+
+// AddNode adds a node with the provided name and returns it.  If the node
+// already exists, it is returned.
+func (n *Network) AddNode(name string) *Node {
+	if node, ok := n.nodes[name]; ok {
+		return node
+	}
+	node := &Node{
+		Name:  name,
+		Inbox: simgo.NewStore(n.env, 0),
+		net:   n,
+	}
+	n.nodes[name] = node
+	return node
+}
+
+// Node returns the node with the provided name, or nil if there is none.
+func (n *Network) Node(name string) *Node {
+	return n.nodes[name]
+}
+
+// Nodes returns the names of the nodes, sorted.
+func (n *Network) Nodes() []string {
+	names := make([]string, 0, len(n.nodes))
+	for name := range n.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Link configures the directed link from one node to another, adding the
+// nodes if needed.  Reconfiguring a link keeps its statistics.
+func (n *Network) Link(from, to string, cfg LinkConfig) {
+	n.AddNode(from)
+	n.AddNode(to)
+	key := linkKey{from, to}
+	if l, ok := n.links[key]; ok {
+		l.cfg = cfg
+		return
+	}
+	n.links[key] = &link{
+		cfg:     cfg,
+		rng:     n.rng.Named(fmt.Sprintf("link:%s->%s", from, to)),
+		latency: stats.NewTally(),
+	}
+}
+
+// Connect configures the links between two nodes in both directions.
+func (n *Network) Connect(a, b string, cfg LinkConfig) {
+	n.Link(a, b, cfg)
+	n.Link(b, a, cfg)
+}
+
+// Stats returns the statistics of the link from one node to another.
+func (n *Network) Stats(from, to string) LinkStats {
+	l, ok := n.links[linkKey{from, to}]
+	if !ok {
+		return LinkStats{}
+	}
+	s := l.stats
+	if l.latency.Count() > 0 {
+		s.Latency = l.latency.Mean()
+		s.MaxLatency = l.latency.Max()
+	}
+	return s
+}
+
+// Partition schedules a partition that splits the nodes into the provided
+// groups from the simulation time start until the time heal.  While the
+// partition is active, messages between nodes of different groups are
+// dropped, both when they are sent and when they would be delivered.  Nodes
+// that are not in any group are not affected.  A heal time of 0 means the
+// partition never heals.
+func (n *Network) Partition(start, heal uint64, groups ...[]string) error {
+	if start < n.env.Now || (heal != 0 && heal <= start) {
+		return errgo.Newf("invalid partition from %d to %d at time %d", start, heal, n.env.Now)
+	}
+	p := &partition{groups: make(map[string]int)}
+	for i, group := range groups {
+		for _, name := range group {
+			p.groups[name] = i
+		}
+	}
+	n.at(start, "partition", func() {
+		n.partitions = append(n.partitions, p)
+	})
+	if heal != 0 {
+		n.at(heal, "heal", func() {
+			for i, active := range n.partitions {
+				if active == p {
+					n.partitions = append(n.partitions[:i], n.partitions[i+1:]...)
+					break
+				}
+			}
+		})
+	}
+	return nil
+}
+
+// Partitioned returns whether the nodes are currently separated by a
+// partition.
+func (n *Network) Partitioned(a, b string) bool {
+	for _, p := range n.partitions {
+		if p.separates(a, b) {
+			return true
+		}
+	}
+	return false
+}
+
+// send sends a message from one node to another.
+func (n *Network) send(from, to string, payload interface{}, size int) error {
+	l, ok := n.links[linkKey{from, to}]
+	if !ok {
+		return errgo.Newf("no link from %q to %q", from, to)
+	}
+	l.seq++
+	msg := &Message{
+		From:    from,
+		To:      to,
+		Payload: payload,
+		Size:    size,
+		Seq:     l.seq,
+		Sent:    n.env.Now,
+	}
+	l.stats.Sent++
+	l.stats.Bytes += size
+	if n.Partitioned(from, to) {
+		l.stats.Partitioned++
+		return nil
+	}
+	// The message occupies the link while it is serialized.
+	departure := n.env.Now
+	if l.busyUntil > departure {
+		departure = l.busyUntil
+	}
+	if l.cfg.Bandwidth > 0 {
+		departure += uint64(math.Ceil(float64(size) / l.cfg.Bandwidth))
+	}
+	l.busyUntil = departure
+	if l.chance(l.cfg.Loss) {
+		l.stats.Lost++
+		return nil
+	}
+
+	arrival := departure + l.delay()
+	if l.chance(l.cfg.Reorder) {
+		l.stats.Reordered++
+		arrival += l.delay()
+	} else {
+		// Keep the order of messages that are not reordered.
+		if arrival < l.lastArrival {
+			arrival = l.lastArrival
+		}
+		l.lastArrival = arrival
+	}
+	n.deliver(l, msg, arrival)
+	if l.chance(l.cfg.Duplicate) {
+		l.stats.Duplicated++
+		dup := *msg
+		dup.Duplicate = true
+		n.deliver(l, &dup, departure+l.delay())
+	}
+	return nil
+}
+
+// deliver schedules the delivery of a message at the provided time.
+func (n *Network) deliver(l *link, msg *Message, at uint64) {
+	n.at(at, "deliver", func() {
+		if n.Partitioned(msg.From, msg.To) {
+			l.stats.Partitioned++
+			return
+		}
+		l.stats.Delivered++
+		l.latency.Add(float64(n.env.Now - msg.Sent))
+		n.nodes[msg.To].receive(msg)
+	})
+}
+
+// at calls fn at the provided simulation time, from the callback of a
+// Timeout with the provided name.
+func (n *Network) at(t uint64, name string, fn func()) {
+	to := simgo.NewTimeout(n.env, t-n.env.Now, nil, simgo.WithName("simnet."+name))
+	to.AddCallback(func(*simgo.Event) { fn() })
+	to.Schedule(n.env)
+}
+
+// A Node is a node of a network.
+type Node struct {
+	// Name is the name of the node
+	Name string
+	// Inbox holds the messages delivered to the node
+	Inbox *simgo.Store
+	net   *Network
+	// stack holds the connections of the node, if any
+	stack *stack
+}
+
+// Send sends a message with the provided payload and size (in bytes) to the
+// node with the provided name.  It returns an error if there is no link to
+// that node.  Sending never blocks: the message is delivered later (or
+// dropped) by the network.
+func (node *Node) Send(to string, payload interface{}, size int) error {
+	return node.net.send(node.Name, to, payload, size)
+}
+
+// Recv returns an event that is triggered with the next *Message delivered to
+// the node.
+func (node *Node) Recv() *simgo.Event {
+	return node.Inbox.Get()
+}
+
+// receive handles a message delivered to the node: segments of connections
+// go to the node's stack, and other messages to its inbox.
+func (node *Node) receive(msg *Message) {
+	if seg, ok := msg.Payload.(*segment); ok {
+		node.getStack().handle(seg)
+		return
+	}
+	node.Inbox.Put(msg)
+}
+
+// linkKey identifies a directed link.
+type linkKey struct {
+	from, to string
+}
+
+// link is the state of a directed link.
+type link struct {
+	cfg LinkConfig
+	rng *random.Stream
+	// seq is the sequence number of the last message sent
+	seq uint64
+	// busyUntil is the time the last message is done being serialized
+	busyUntil uint64
+	// lastArrival is the arrival time of the last message that was not
+	// reordered
+	lastArrival uint64
+	stats       LinkStats
+	latency     *stats.Tally
+}
+
+// delay draws a propagation delay.
+func (l *link) delay() uint64 {
+	if l.cfg.Latency == nil {
+		return 0
+	}
+	return l.rng.Delay(l.cfg.Latency)
+}
+
+// chance returns true with the provided probability.  No number is drawn if
+// the probability is 0, so enabling one fault does not change the others.
+func (l *link) chance(p float64) bool {
+	return p > 0 && l.rng.Float64() < p
+}
+
+// partition is a partition of the network.
+type partition struct {
+	// groups maps node names to their group
+	groups map[string]int
+}
+
+// separates returns whether the nodes are in different groups.
+func (p *partition) separates(a, b string) bool {
+	ga, okA := p.groups[a]
+	gb, okB := p.groups[b]
+	return okA && okB && ga != gb
+}
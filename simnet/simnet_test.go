@@ -0,0 +1,170 @@
+package simnet
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+)
+
+// receive starts a process that receives messages on the node and records
+// their payloads and arrival times.
+func receive(env *simgo.Environment, node *Node, log *[]interface{}) {
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		for {
+			msg := pc.Yield(node.Recv()).(*Message)
+			*log = append(*log, msg.Payload, env.Now)
+		}
+	}, simgo.WithName("receive")))
+	p.Init()
+}
+
+func TestLatencyAndBandwidth(t *testing.T) {
+	env := simgo.NewEnvironment()
+	net := New(env, random.New(1))
+	a := net.AddNode("a")
+	net.Link("a", "b", LinkConfig{Latency: random.Constant(10), Bandwidth: 100})
+	var log []interface{}
+	receive(env, net.Node("b"), &log)
+	// Each message takes 5 units to serialize, so the second one leaves
+	// once the first has.
+	for _, payload := range []string{"spam", "eggs"} {
+		if err := a.Send("b", payload, 500); err != nil {
+			t.Fatalf("a.Send() = %v, want: nil", err)
+		}
+	}
+	env.Run(uint64(100))
+	want := []interface{}{"spam", uint64(15), "eggs", uint64(20)}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want: %v", log, want)
+	}
+	s := net.Stats("a", "b")
+	if s.Sent != 2 || s.Delivered != 2 || s.Bytes != 1000 {
+		t.Errorf("Stats() = %+v, want: 2 sent, 2 delivered, 1000 bytes", s)
+	}
+	if s.Latency != 17.5 || s.MaxLatency != 20 {
+		t.Errorf("latency = %v (max %v), want: 17.5 (max 20)", s.Latency, s.MaxLatency)
+	}
+	if err := a.Send("c", "spam", 1); err == nil {
+		t.Errorf("a.Send() = nil, want: an error without a link")
+	}
+}
+
+func TestOrder(t *testing.T) {
+	env := simgo.NewEnvironment()
+	net := New(env, random.New(1))
+	net.Link("a", "b", LinkConfig{Latency: random.Exponential(10)})
+	var log []interface{}
+	receive(env, net.Node("b"), &log)
+	for i := 0; i < 50; i++ {
+		net.Node("a").Send("b", i, 1)
+	}
+	env.Run(uint64(10000))
+	for i := 0; i < 50; i++ {
+		if log[2*i] != i {
+			t.Fatalf("message %d = %v, want: messages in order", i, log[2*i])
+		}
+	}
+}
+
+func TestFaults(t *testing.T) {
+	env := simgo.NewEnvironment()
+	net := New(env, random.New(1))
+	cfg := LinkConfig{
+		Latency:   random.Uniform(1, 100),
+		Loss:      0.2,
+		Duplicate: 0.1,
+		Reorder:   0.3,
+	}
+	net.Link("a", "b", cfg)
+	var log []interface{}
+	receive(env, net.Node("b"), &log)
+	const n = 1000
+	for i := 0; i < n; i++ {
+		net.Node("a").Send("b", i, 1)
+	}
+	env.Run(uint64(10000))
+	s := net.Stats("a", "b")
+	if s.Delivered != n-s.Lost+s.Duplicated {
+		t.Errorf("Delivered = %d, want: %d", s.Delivered, n-s.Lost+s.Duplicated)
+	}
+	if len(log)/2 != s.Delivered {
+		t.Errorf("received %d messages, want: %d", len(log)/2, s.Delivered)
+	}
+	check := func(name string, got int, p float64) {
+		if want := p * n; float64(got) < 0.7*want || float64(got) > 1.3*want {
+			t.Errorf("%s = %d, want: about %v", name, got, want)
+		}
+	}
+	check("Lost", s.Lost, cfg.Loss)
+	check("Duplicated", s.Duplicated, (1-cfg.Loss)*cfg.Duplicate)
+	check("Reordered", s.Reordered, (1-cfg.Loss)*cfg.Reorder)
+	inversions := 0
+	for i := 2; i < len(log); i += 2 {
+		if log[i].(int) < log[i-2].(int) {
+			inversions++
+		}
+	}
+	if inversions == 0 {
+		t.Errorf("inversions = 0, want: some messages out of order")
+	}
+
+	// The same seed gives the same deliveries.
+	env2 := simgo.NewEnvironment()
+	net2 := New(env2, random.New(1))
+	net2.Link("a", "b", cfg)
+	var log2 []interface{}
+	receive(env2, net2.Node("b"), &log2)
+	for i := 0; i < n; i++ {
+		net2.Node("a").Send("b", i, 1)
+	}
+	env2.Run(uint64(10000))
+	if !reflect.DeepEqual(log, log2) {
+		t.Errorf("deliveries differ with the same seed")
+	}
+}
+
+func TestPartition(t *testing.T) {
+	env := simgo.NewEnvironment()
+	net := New(env, random.New(1))
+	cfg := LinkConfig{Latency: random.Constant(10)}
+	net.Connect("a", "b", cfg)
+	net.Connect("a", "c", cfg)
+	if err := net.Partition(20, 50, []string{"a"}, []string{"b"}); err != nil {
+		t.Fatalf("Partition() = %v, want: nil", err)
+	}
+	if err := net.Partition(10, 5); err == nil {
+		t.Errorf("Partition() = nil, want: an error when healing before the start")
+	}
+	var logB, logC []interface{}
+	receive(env, net.Node("b"), &logB)
+	receive(env, net.Node("c"), &logC)
+	sender := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		// Sent at 0, 15, 30, 45 and 60: the message sent at 15 arrives
+		// during the partition.
+		for i := 0; i < 5; i++ {
+			net.Node("a").Send("b", i, 1)
+			net.Node("a").Send("c", i, 1)
+			to := simgo.NewTimeout(env, 15, nil)
+			to.Schedule(env)
+			pc.Yield(to.Event)
+		}
+		return nil
+	}))
+	sender.Init()
+	env.Run(uint64(100))
+	wantB := []interface{}{0, uint64(10), 4, uint64(70)}
+	if !reflect.DeepEqual(logB, wantB) {
+		t.Errorf("b received %v, want: %v", logB, wantB)
+	}
+	if len(logC) != 10 {
+		t.Errorf("c received %v, want: all 5 messages", logC)
+	}
+	if s := net.Stats("a", "b"); s.Partitioned != 3 {
+		t.Errorf("Partitioned = %d, want: 3", s.Partitioned)
+	}
+	if net.Partitioned("a", "b") {
+		t.Errorf("Partitioned() = true, want: false once healed")
+	}
+}
@@ -0,0 +1,511 @@
+// Package simsync provides synchronization primitives for simgo processes,
+// like those of the sync package but in simulation time.
+//
+// Operations that may have to wait (e.g., Mutex.Lock()) return an event that
+// is triggered once they are done, so processes wait for them with
+// ProcComm.Yield(), and can combine them with AllOf() and AnyOf():
+//
+//	mon := simsync.NewMonitor(env)
+//	mu := mon.Mutex("db")
+//	// In a process:
+//	pc.Yield(mu.Lock())
+//	defer mu.Unlock()
+//
+// Waiting requests are served in the order they were made.  A request that is
+// given up on (e.g., when a timeout wins an AnyOf()) must be withdrawn with
+// Cancel(), or the primitive would be granted to no one.
+//
+// The Monitor that creates the primitives keeps track of the processes that
+// hold and wait for them: once Run() returns because no events are left,
+// Diagnose() describes the processes that are blocked for good, and the
+// cycles of processes waiting for each other.
+package simsync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bgmerrell/simgo"
+)
+
+// A Monitor creates synchronization primitives and keeps track of the
+// processes that hold and wait for them, to diagnose deadlocks.
+type Monitor struct {
+	env   *simgo.Environment
+	prims []*primitive
+}
+
+// NewMonitor returns a new Monitor for the provided Environment.
+func NewMonitor(env *simgo.Environment) *Monitor {
+	return &Monitor{env: env}
+}
+
+// newPrimitive registers and returns the state of a new primitive.
+func (m *Monitor) newPrimitive(kind, name string) *primitive {
+	p := &primitive{env: m.env, kind: kind, name: name}
+	m.prims = append(m.prims, p)
+	return p
+}
+
+// primitive is the state shared by all primitives: the requests waiting for
+// the primitive and the processes holding it.
+type primitive struct {
+	env        *simgo.Environment
+	kind, name string
+	waiters    []*waiter
+	holders    []holder
+}
+
+// A waiter is a pending request.
+type waiter struct {
+	event *simgo.Event
+	// proc is the process that made the request, if any
+	proc *simgo.Process
+	// n is the number of units requested
+	n int
+}
+
+// A holder is a process holding units of a primitive.
+type holder struct {
+	proc *simgo.Process
+	n    int
+}
+
+// wait adds a request for n units made by the active process.
+func (p *primitive) wait(op string, n int) *waiter {
+	w := &waiter{
+		event: simgo.NewEvent(p.env, simgo.WithName(p.kind+"."+op)),
+		proc:  p.env.ActiveProcess,
+		n:     n,
+	}
+	p.waiters = append(p.waiters, w)
+	return w
+}
+
+// cancel withdraws a pending request and returns whether it was pending.
+func (p *primitive) cancel(e *simgo.Event) bool {
+	for i, w := range p.waiters {
+		if w.event == e {
+			p.waiters = append(p.waiters[:i], p.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// grant triggers the first request, which holds its units from now on.
+func (p *primitive) grant() {
+	w := p.waiters[0]
+	p.waiters = p.waiters[1:]
+	for i := range p.holders {
+		if p.holders[i].proc == w.proc {
+			p.holders[i].n += w.n
+			w.event.Succeed(nil)
+			return
+		}
+	}
+	p.holders = append(p.holders, holder{w.proc, w.n})
+	w.event.Succeed(nil)
+}
+
+// release releases n units, taken from those held by the active process
+// first.
+func (p *primitive) release(n int) {
+	for n > 0 && len(p.holders) > 0 {
+		i := 0
+		for j, h := range p.holders {
+			if h.proc == p.env.ActiveProcess {
+				i = j
+				break
+			}
+		}
+		taken := n
+		if taken > p.holders[i].n {
+			taken = p.holders[i].n
+		}
+		p.holders[i].n -= taken
+		n -= taken
+		if p.holders[i].n == 0 {
+			p.holders = append(p.holders[:i], p.holders[i+1:]...)
+		}
+	}
+}
+
+// wakeAll triggers all pending requests with the provided value.
+func (p *primitive) wakeAll(val interface{}) {
+	for _, w := range p.waiters {
+		w.event.Succeed(val)
+	}
+	p.waiters = nil
+}
+
+// A Semaphore is a counting semaphore.
+type Semaphore struct {
+	p         *primitive
+	capacity  int
+	available int
+}
+
+// Semaphore returns a new Semaphore with the provided number of units.  It
+// panics if capacity is not positive.
+func (m *Monitor) Semaphore(name string, capacity int) *Semaphore {
+	if capacity < 1 {
+		panic(fmt.Sprintf("simsync: semaphore %q needs a positive capacity", name))
+	}
+	return &Semaphore{
+		p:         m.newPrimitive("Semaphore", name),
+		capacity:  capacity,
+		available: capacity,
+	}
+}
+
+// Acquire returns an event that is triggered once n units have been acquired.
+// Requests are served in order: a request waits for the requests made before
+// it, even if enough units are available for it.  Acquire panics if n is not
+// between 1 and the capacity of the semaphore.
+func (s *Semaphore) Acquire(n int) *simgo.Event {
+	if n < 1 || n > s.capacity {
+		panic(fmt.Sprintf("simsync: cannot acquire %d units of semaphore %q of capacity %d", n, s.p.name, s.capacity))
+	}
+	w := s.p.wait("Acquire", n)
+	s.dispatch()
+	return w.event
+}
+
+// Release releases n units.  It panics if more units are released than were
+// acquired.
+func (s *Semaphore) Release(n int) {
+	if n < 1 || s.available+n > s.capacity {
+		panic(fmt.Sprintf("simsync: release of %d units of semaphore %q with %d acquired", n, s.p.name, s.capacity-s.available))
+	}
+	s.available += n
+	s.p.release(n)
+	s.dispatch()
+}
+
+// Available returns the number of units available.
+func (s *Semaphore) Available() int {
+	return s.available
+}
+
+// Cancel withdraws a pending Acquire() request and returns whether it was
+// pending.
+func (s *Semaphore) Cancel(e *simgo.Event) bool {
+	if !s.p.cancel(e) {
+		return false
+	}
+	s.dispatch()
+	return true
+}
+
+// dispatch serves the pending requests that can be served, in order.
+func (s *Semaphore) dispatch() {
+	for len(s.p.waiters) > 0 && s.p.waiters[0].n <= s.available {
+		s.available -= s.p.waiters[0].n
+		s.p.grant()
+	}
+}
+
+// A Mutex is a mutual exclusion lock.
+type Mutex struct {
+	s *Semaphore
+}
+
+// Mutex returns a new, unlocked Mutex.
+func (m *Monitor) Mutex(name string) *Mutex {
+	s := m.Semaphore(name, 1)
+	s.p.kind = "Mutex"
+	return &Mutex{s}
+}
+
+// Lock returns an event that is triggered once the mutex is locked.
+func (mu *Mutex) Lock() *simgo.Event {
+	w := mu.s.p.wait("Lock", 1)
+	mu.s.dispatch()
+	return w.event
+}
+
+// Unlock unlocks the mutex, which may be locked by another process.  It
+// panics if the mutex is not locked.
+func (mu *Mutex) Unlock() {
+	if mu.s.available == 1 {
+		panic(fmt.Sprintf("simsync: unlock of unlocked mutex %q", mu.s.p.name))
+	}
+	mu.s.Release(1)
+}
+
+// Locked returns whether the mutex is locked.
+func (mu *Mutex) Locked() bool {
+	return mu.s.available == 0
+}
+
+// Cancel withdraws a pending Lock() request and returns whether it was
+// pending.
+func (mu *Mutex) Cancel(e *simgo.Event) bool {
+	return mu.s.Cancel(e)
+}
+
+// A WaitGroup waits for a collection of processes to finish, like a
+// sync.WaitGroup.
+type WaitGroup struct {
+	p     *primitive
+	count int
+}
+
+// WaitGroup returns a new WaitGroup with a count of zero.
+func (m *Monitor) WaitGroup(name string) *WaitGroup {
+	return &WaitGroup{p: m.newPrimitive("WaitGroup", name)}
+}
+
+// Add adds delta, which may be negative, to the count.  The Wait() events are
+// triggered once the count is zero.  Add panics if the count becomes
+// negative.
+func (wg *WaitGroup) Add(delta int) {
+	wg.count += delta
+	if wg.count < 0 {
+		panic(fmt.Sprintf("simsync: negative count of wait group %q", wg.p.name))
+	}
+	if wg.count == 0 {
+		wg.p.wakeAll(nil)
+	}
+}
+
+// Done decrements the count by one.
+func (wg *WaitGroup) Done() {
+	wg.Add(-1)
+}
+
+// Wait returns an event that is triggered once the count is zero.
+func (wg *WaitGroup) Wait() *simgo.Event {
+	w := wg.p.wait("Wait", 0)
+	if wg.count == 0 {
+		wg.p.wakeAll(nil)
+	}
+	return w.event
+}
+
+// Cancel withdraws a pending Wait() request and returns whether it was
+// pending.
+func (wg *WaitGroup) Cancel(e *simgo.Event) bool {
+	return wg.p.cancel(e)
+}
+
+// A Latch is a one-shot gate that opens once it has been counted down to
+// zero.
+type Latch struct {
+	p     *primitive
+	count int
+}
+
+// Latch returns a new Latch that opens after count calls to CountDown().  A
+// count of 0 returns a latch that is already open.  Latch panics if count is
+// negative.
+func (m *Monitor) Latch(name string, count int) *Latch {
+	if count < 0 {
+		panic(fmt.Sprintf("simsync: latch %q needs a count of at least zero", name))
+	}
+	return &Latch{p: m.newPrimitive("Latch", name), count: count}
+}
+
+// CountDown decrements the count, and opens the latch once it reaches zero.
+// Calls once the latch is open do nothing.
+func (l *Latch) CountDown() {
+	if l.count == 0 {
+		return
+	}
+	l.count--
+	if l.count == 0 {
+		l.p.wakeAll(nil)
+	}
+}
+
+// Count returns the current count.
+func (l *Latch) Count() int {
+	return l.count
+}
+
+// Wait returns an event that is triggered once the latch is open.
+func (l *Latch) Wait() *simgo.Event {
+	w := l.p.wait("Wait", 0)
+	if l.count == 0 {
+		l.p.wakeAll(nil)
+	}
+	return w.event
+}
+
+// Cancel withdraws a pending Wait() request and returns whether it was
+// pending.
+func (l *Latch) Cancel(e *simgo.Event) bool {
+	return l.p.cancel(e)
+}
+
+// A Barrier is a reusable barrier for a number of parties: it trips once
+// all parties are waiting, releasing them, and then waits for the parties
+// again.
+type Barrier struct {
+	p       *primitive
+	parties int
+	// generation is the number of times the barrier has tripped
+	generation int
+}
+
+// Barrier returns a new Barrier for the provided number of parties.  It
+// panics if parties is not positive.
+func (m *Monitor) Barrier(name string, parties int) *Barrier {
+	if parties < 1 {
+		panic(fmt.Sprintf("simsync: barrier %q needs at least one party", name))
+	}
+	return &Barrier{p: m.newPrimitive("Barrier", name), parties: parties}
+}
+
+// Wait returns an event that is triggered once all parties are waiting.  The
+// value of the event is the generation of the barrier: 0 the first time it
+// trips, 1 the second time, and so on.
+func (b *Barrier) Wait() *simgo.Event {
+	w := b.p.wait("Wait", 0)
+	if len(b.p.waiters) == b.parties {
+		b.p.wakeAll(b.generation)
+		b.generation++
+	}
+	return w.event
+}
+
+// Waiting returns the number of parties waiting.
+func (b *Barrier) Waiting() int {
+	return len(b.p.waiters)
+}
+
+// Cancel withdraws a pending Wait() request and returns whether it was
+// pending.
+func (b *Barrier) Cancel(e *simgo.Event) bool {
+	return b.p.cancel(e)
+}
+
+// A Deadlock describes processes that are blocked waiting on primitives.
+type Deadlock struct {
+	// Waits are the pending requests, in the order the primitives were
+	// created and the requests were made
+	Waits []Wait
+	// Cycle is a cycle of processes that wait for primitives held by the
+	// next process (the last one waiting for the first one), if any
+	Cycle []*simgo.Process
+}
+
+// A Wait is a pending request.
+type Wait struct {
+	// Process is the process that made the request, if any
+	Process *simgo.Process
+	// Kind and Name are the kind (e.g., "Mutex") and name of the primitive
+	Kind, Name string
+	// Holders are the processes holding the primitive
+	Holders []*simgo.Process
+}
+
+func (w Wait) String() string {
+	s := fmt.Sprintf("%s waits for %s %q", procName(w.Process), w.Kind, w.Name)
+	if len(w.Holders) > 0 {
+		names := make([]string, len(w.Holders))
+		for i, h := range w.Holders {
+			names[i] = procName(h)
+		}
+		s += ", held by " + strings.Join(names, ", ")
+	}
+	return s
+}
+
+// Error describes the blocked processes and the cycle, if any, e.g.:
+//
+//	deadlock: 2 requests are blocked:
+//		"a" waits for Mutex "m2", held by "b"
+//		"b" waits for Mutex "m1", held by "a"
+//		cycle: "a" -> "b" -> "a"
+func (d *Deadlock) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "deadlock: %d requests are blocked:", len(d.Waits))
+	for _, w := range d.Waits {
+		fmt.Fprintf(&b, "\n\t%s", w)
+	}
+	if len(d.Cycle) > 0 {
+		names := make([]string, 0, len(d.Cycle)+1)
+		for _, p := range append(d.Cycle, d.Cycle[0]) {
+			names = append(names, procName(p))
+		}
+		fmt.Fprintf(&b, "\n\tcycle: %s", strings.Join(names, " -> "))
+	}
+	return b.String()
+}
+
+// Diagnose returns a *Deadlock describing the requests that are waiting on
+// the primitives of the monitor, or nil if there are none.  Call it once
+// Run() has returned because no events are left: the requests still waiting
+// then wait for good.
+func (m *Monitor) Diagnose() error {
+	d := &Deadlock{}
+	// waitsFor maps processes to the processes holding what they wait for.
+	waitsFor := make(map[*simgo.Process][]*simgo.Process)
+	var procs []*simgo.Process
+	for _, p := range m.prims {
+		var holders []*simgo.Process
+		for _, h := range p.holders {
+			holders = append(holders, h.proc)
+		}
+		for _, w := range p.waiters {
+			d.Waits = append(d.Waits, Wait{w.proc, p.kind, p.name, holders})
+			if w.proc == nil {
+				continue
+			}
+			if _, ok := waitsFor[w.proc]; !ok {
+				procs = append(procs, w.proc)
+			}
+			waitsFor[w.proc] = append(waitsFor[w.proc], holders...)
+		}
+	}
+	if len(d.Waits) == 0 {
+		return nil
+	}
+	visited := make(map[*simgo.Process]bool)
+	for _, p := range procs {
+		if cycle := findCycle(p, waitsFor, visited, nil); cycle != nil {
+			d.Cycle = cycle
+			break
+		}
+	}
+	return d
+}
+
+// findCycle returns a cycle of the wait-for graph reachable from p, which
+// follows the path taken so far, or nil if there is none that goes through
+// processes not visited yet.
+func findCycle(p *simgo.Process, waitsFor map[*simgo.Process][]*simgo.Process, visited map[*simgo.Process]bool, path []*simgo.Process) []*simgo.Process {
+	for i, q := range path {
+		if q == p {
+			return path[i:]
+		}
+	}
+	if visited[p] {
+		return nil
+	}
+	visited[p] = true
+	path = append(path, p)
+	for _, q := range waitsFor[p] {
+		if q == nil {
+			continue
+		}
+		if cycle := findCycle(q, waitsFor, visited, path); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// procName returns the quoted name of a process.
+func procName(p *simgo.Process) string {
+	switch {
+	case p == nil:
+		return "(no process)"
+	case p.Name() == "":
+		return "(unnamed process)"
+	default:
+		return fmt.Sprintf("%q", p.Name())
+	}
+}
@@ -0,0 +1,213 @@
+package simsync
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+)
+
+// spawn starts a process with the provided name running fn.
+func spawn(env *simgo.Environment, name string, fn func(pc *simgo.ProcComm)) *simgo.Process {
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		fn(pc)
+		return nil
+	}, simgo.WithName(name)))
+	p.Init()
+	return p
+}
+
+// sleep suspends the process for the provided delay.
+func sleep(env *simgo.Environment, pc *simgo.ProcComm, delay uint64) {
+	to := simgo.NewTimeout(env, delay, nil)
+	to.Schedule(env)
+	pc.Yield(to.Event)
+}
+
+func TestMutex(t *testing.T) {
+	env := simgo.NewEnvironment()
+	mon := NewMonitor(env)
+	mu := mon.Mutex("mu")
+	var log []interface{}
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		spawn(env, name, func(pc *simgo.ProcComm) {
+			pc.Yield(mu.Lock())
+			log = append(log, name, env.Now)
+			sleep(env, pc, 10)
+			mu.Unlock()
+		})
+	}
+	env.Run(nil)
+	want := []interface{}{"a", uint64(0), "b", uint64(10), "c", uint64(20)}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want: %v", log, want)
+	}
+	if mu.Locked() {
+		t.Errorf("mu.Locked() = true, want: false")
+	}
+	if err := mon.Diagnose(); err != nil {
+		t.Errorf("mon.Diagnose() = %v, want: nil", err)
+	}
+}
+
+func TestSemaphore(t *testing.T) {
+	env := simgo.NewEnvironment()
+	mon := NewMonitor(env)
+	s := mon.Semaphore("s", 3)
+	var log []interface{}
+	acquire := func(name string, n int, at, hold uint64) {
+		spawn(env, name, func(pc *simgo.ProcComm) {
+			sleep(env, pc, at)
+			pc.Yield(s.Acquire(n))
+			log = append(log, name, env.Now)
+			sleep(env, pc, hold)
+			s.Release(n)
+		})
+	}
+	// "big" waits for "a" to release, and "small" waits behind "big" even
+	// though a unit is available.
+	acquire("a", 2, 0, 10)
+	acquire("big", 3, 1, 10)
+	acquire("small", 1, 2, 10)
+	env.Run(nil)
+	want := []interface{}{"a", uint64(0), "big", uint64(10), "small", uint64(20)}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want: %v", log, want)
+	}
+	if s.Available() != 3 {
+		t.Errorf("s.Available() = %d, want: 3", s.Available())
+	}
+}
+
+func TestCancel(t *testing.T) {
+	env := simgo.NewEnvironment()
+	mon := NewMonitor(env)
+	mu := mon.Mutex("mu")
+	var gotLock bool
+	spawn(env, "holder", func(pc *simgo.ProcComm) {
+		pc.Yield(mu.Lock())
+		sleep(env, pc, 100)
+		mu.Unlock()
+	})
+	spawn(env, "impatient", func(pc *simgo.ProcComm) {
+		lock := mu.Lock()
+		to := simgo.NewTimeout(env, 10, nil)
+		to.Schedule(env)
+		pc.Yield(simgo.AnyOf(env, []*simgo.Event{lock, to.Event}).Event)
+		if gotLock = lock.Triggered(); !gotLock {
+			mu.Cancel(lock)
+		}
+	})
+	env.Run(nil)
+	if gotLock || mu.Locked() {
+		t.Errorf("gotLock = %v, mu.Locked() = %v, want: false, false", gotLock, mu.Locked())
+	}
+}
+
+func TestWaitGroupAndLatch(t *testing.T) {
+	env := simgo.NewEnvironment()
+	mon := NewMonitor(env)
+	wg := mon.WaitGroup("workers")
+	latch := mon.Latch("start", 1)
+	var log []interface{}
+	for i := 1; i <= 3; i++ {
+		i := i
+		wg.Add(1)
+		spawn(env, "worker", func(pc *simgo.ProcComm) {
+			pc.Yield(latch.Wait())
+			sleep(env, pc, uint64(10*i))
+			wg.Done()
+		})
+	}
+	spawn(env, "main", func(pc *simgo.ProcComm) {
+		sleep(env, pc, 5)
+		latch.CountDown()
+		pc.Yield(wg.Wait())
+		log = append(log, "done", env.Now)
+		// Both are open from now on.
+		pc.Yield(simgo.AllOf(env, []*simgo.Event{wg.Wait(), latch.Wait()}).Event)
+		log = append(log, "again", env.Now)
+	})
+	env.Run(nil)
+	want := []interface{}{"done", uint64(35), "again", uint64(35)}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want: %v", log, want)
+	}
+
+	// A latch with a count of zero is open, and a negative count panics.
+	if open := mon.Latch("open", 0).Wait(); !open.Triggered() {
+		t.Errorf("open.Triggered() = false, want: true for a count of zero")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("mon.Latch() did not panic on a negative count")
+		}
+	}()
+	mon.Latch("negative", -1)
+}
+
+func TestBarrier(t *testing.T) {
+	env := simgo.NewEnvironment()
+	mon := NewMonitor(env)
+	b := mon.Barrier("round", 3)
+	var log []interface{}
+	for i := 1; i <= 3; i++ {
+		i := i
+		spawn(env, "party", func(pc *simgo.ProcComm) {
+			for round := 0; round < 2; round++ {
+				sleep(env, pc, uint64(i))
+				gen := pc.Yield(b.Wait())
+				if i == 1 {
+					log = append(log, gen, env.Now)
+				}
+			}
+		})
+	}
+	env.Run(nil)
+	want := []interface{}{0, uint64(3), 1, uint64(6)}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want: %v", log, want)
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	env := simgo.NewEnvironment()
+	mon := NewMonitor(env)
+	m1, m2 := mon.Mutex("m1"), mon.Mutex("m2")
+	lockBoth := func(first, second *Mutex) func(pc *simgo.ProcComm) {
+		return func(pc *simgo.ProcComm) {
+			pc.Yield(first.Lock())
+			sleep(env, pc, 10)
+			pc.Yield(second.Lock())
+		}
+	}
+	spawn(env, "a", lockBoth(m1, m2))
+	spawn(env, "b", lockBoth(m2, m1))
+	wg := mon.WaitGroup("never")
+	wg.Add(1)
+	spawn(env, "c", func(pc *simgo.ProcComm) {
+		pc.Yield(wg.Wait())
+	})
+	env.Run(nil)
+	err := mon.Diagnose()
+	d, ok := err.(*Deadlock)
+	if !ok {
+		t.Fatalf("mon.Diagnose() = %v, want: a *Deadlock", err)
+	}
+	if len(d.Waits) != 3 || len(d.Cycle) != 2 {
+		t.Errorf("Diagnose() = %+v, want: 3 waits and a cycle of 2", d)
+	}
+	for _, s := range []string{
+		`deadlock: 3 requests are blocked:`,
+		`"b" waits for Mutex "m1", held by "a"`,
+		`"a" waits for Mutex "m2", held by "b"`,
+		`"c" waits for WaitGroup "never"`,
+		`cycle: "b" -> "a" -> "b"`,
+	} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("err.Error() = %q, want: it to contain %q", err.Error(), s)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package simtest
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+)
+
+// checkPriority is the priority of the events that run assertions, so they
+// are processed after all the other events of the same time.
+const checkPriority = simgo.PriorityNormal + 1
+
+// AssertAt checks the predicate at the provided simulation time, once all the
+// other events of that time have been processed, and reports an error if it
+// is false.  It also reports an error at the end of the test if the
+// simulation never reaches that time.
+//
+// The check is made by a process named "AssertAt", whose events show up in
+// traces.  Its pending check keeps Run(nil) from returning before the time of
+// the check.
+func AssertAt(t testing.TB, env *simgo.Environment, time uint64, predicate func() bool) {
+	t.Helper()
+	assertAt(t, env, time, predicate, func() string { return "assertion failed" })
+}
+
+// ExpectProcessFinishedBy reports an error if the process function of p has
+// not returned by the provided simulation time.  It is checked like
+// AssertAt().
+func ExpectProcessFinishedBy(t testing.TB, env *simgo.Environment, p *simgo.Process, time uint64) {
+	t.Helper()
+	assertAt(t, env, time, p.Triggered, func() string { return fmt.Sprintf("%s has not finished", p) })
+}
+
+// assertAt schedules a check of the predicate at the provided time, which
+// reports the failure message if the predicate is false.
+func assertAt(t testing.TB, env *simgo.Environment, time uint64, predicate func() bool, failure func() string) {
+	t.Helper()
+	// Report the location of the caller of AssertAt(), since the check
+	// runs later.
+	_, file, line, _ := runtime.Caller(2)
+	where := fmt.Sprintf("%s:%d", filepath.Base(file), line)
+	if time < env.Now {
+		t.Errorf("%s: cannot check at %d, the simulation is already at %d", where, time, env.Now)
+		return
+	}
+	checked := false
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		check := simgo.NewTimeout(env, 0, nil, simgo.WithName("check"))
+		env.Schedule(check.Event, checkPriority, time-env.Now)
+		pc.Yield(check.Event)
+		checked = true
+		if !predicate() {
+			t.Errorf("%s: %s at %d", where, failure(), env.Now)
+		}
+		return nil
+	}, simgo.WithName("AssertAt")))
+	p.Init()
+	t.Cleanup(func() {
+		if !checked {
+			t.Errorf("%s: check at %d never ran (the simulation stopped at %d)", where, time, env.Now)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package simtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+)
+
+// worker starts a process that works for the provided duration.
+func worker(env *simgo.Environment, duration uint64) *simgo.Process {
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		to := simgo.NewTimeout(env, duration, nil)
+		to.Schedule(env)
+		pc.Yield(to.Event)
+		return nil
+	}, simgo.WithName("worker")))
+	p.Init()
+	return p
+}
+
+func TestAssertAt(t *testing.T) {
+	env := simgo.NewEnvironment()
+	p := worker(env, 10)
+	AssertAt(t, env, 5, func() bool { return env.Now == 5 && !p.Triggered() })
+	// Checks run after the other events of the same time.
+	AssertAt(t, env, 10, p.Triggered)
+	ExpectProcessFinishedBy(t, env, p, 10)
+	env.Run(nil)
+}
+
+func TestAssertAtFailures(t *testing.T) {
+	rt := &recordingT{}
+	env := simgo.NewEnvironment()
+	p := worker(env, 10)
+	AssertAt(rt, env, 3, func() bool { return false })
+	ExpectProcessFinishedBy(rt, env, p, 9)
+	AssertAt(rt, env, 50, func() bool { return true })
+	env.Run(20)
+	rt.cleanup()
+
+	// Errors are reported with the location of the assertion.
+	want := []string{
+		"assertion failed at 3",
+		`Process "worker" (pending) has not finished at 9`,
+		"check at 50 never ran (the simulation stopped at 20)",
+	}
+	if len(rt.errors) != len(want) {
+		t.Fatalf("errors = %q, want: %q", rt.errors, want)
+	}
+	for i := range want {
+		if !strings.HasPrefix(rt.errors[i], "assert_test.go:") || !strings.HasSuffix(rt.errors[i], want[i]) {
+			t.Errorf("errors[%d] = %q, want: %q", i, rt.errors[i], want[i])
+		}
+	}
+}
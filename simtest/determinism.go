@@ -0,0 +1,168 @@
+package simtest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"runtime"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+)
+
+// defaultRuns is the number of runs made by AssertDeterministic() when none
+// is provided.
+const defaultRuns = 3
+
+// A DeterminismOption configures AssertDeterministic().
+type DeterminismOption func(*determinismConfig)
+
+// determinismConfig is the configuration of AssertDeterministic().
+type determinismConfig struct {
+	runs           int
+	varyGOMAXPROCS bool
+}
+
+// Runs sets the number of runs to compare (3 by default).
+func Runs(n int) DeterminismOption {
+	return func(c *determinismConfig) {
+		c.runs = n
+	}
+}
+
+// VaryGOMAXPROCS makes each run use a different GOMAXPROCS value (1, then
+// the number of CPUs, then twice as many, and so on), which exposes races
+// between goroutines started by the model.
+func VaryGOMAXPROCS() DeterminismOption {
+	return func(c *determinismConfig) {
+		c.varyGOMAXPROCS = true
+	}
+}
+
+// AssertDeterministic runs the model several times with the provided seed
+// and reports an error if the runs diverge.  Runs are compared using a
+// rolling hash of the processed events: their time, priority, ID, kind, name
+// and value.  The error reports the first step at which a run differs from
+// the first one.
+//
+// Values are hashed if they are booleans, numbers, strings or errors.  Other
+// values (e.g., the values of conditions, which hold pointers) are only
+// hashed by type, since their formatting may differ between runs.
+func AssertDeterministic(t testing.TB, model Model, seed uint64, opts ...DeterminismOption) {
+	t.Helper()
+	c := determinismConfig{runs: defaultRuns}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.varyGOMAXPROCS {
+		defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	}
+	var first []step
+	for run := 1; run <= c.runs; run++ {
+		if c.varyGOMAXPROCS {
+			procs := 1
+			if run > 1 {
+				procs = (run - 1) * runtime.NumCPU()
+			}
+			runtime.GOMAXPROCS(procs)
+		}
+		steps := hashRun(model, seed)
+		if run == 1 {
+			first = steps
+			continue
+		}
+		if i, ok := diverges(first, steps); ok {
+			t.Errorf("run %d diverges from run 1 at step %d:\n- %s\n+ %s",
+				run, i+1, describe(first, i), describe(steps, i))
+			return
+		}
+	}
+}
+
+// A step is a processed event and the rolling hash of the run up to and
+// including it.
+type step struct {
+	hash uint64
+	desc string
+}
+
+// hashRun runs the model and returns its steps.
+func hashRun(model Model, seed uint64) []step {
+	env := simgo.NewEnvironment()
+	h := &hasher{}
+	env.Observer = h
+	model(env, random.New(seed))
+	return h.steps
+}
+
+// diverges returns the index of the first step at which the runs differ, if
+// any.
+func diverges(a, b []step) (int, bool) {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		if i >= len(a) || i >= len(b) || a[i].hash != b[i].hash {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// describe returns the description of step i, or of the end of the run.
+func describe(steps []step, i int) string {
+	if i >= len(steps) {
+		return "end of run"
+	}
+	return steps[i].desc
+}
+
+// hasher is an Observer that computes a rolling hash of processed events.
+type hasher struct {
+	hash  uint64
+	steps []step
+}
+
+// EventScheduled does nothing.
+func (h *hasher) EventScheduled(event *simgo.Event, time uint64, priority int, eid simgo.EventID) {
+}
+
+// EventProcessed adds the event to the hash.
+func (h *hasher) EventProcessed(event *simgo.Event, time uint64, priority int, eid simgo.EventID) {
+	val, _ := event.Value()
+	desc := fmt.Sprintf("%s %q at %d (priority %d, eid %d, value %s)",
+		event.Kind(), event.Name(), time, priority, eid, hashValue(val))
+	f := fnv.New64a()
+	var prev [8]byte
+	binary.LittleEndian.PutUint64(prev[:], h.hash)
+	f.Write(prev[:])
+	f.Write([]byte(desc))
+	h.hash = f.Sum64()
+	h.steps = append(h.steps, step{h.hash, desc})
+}
+
+// ProcessStarted does nothing.
+func (h *hasher) ProcessStarted(p *simgo.Process) {}
+
+// ProcessResumed does nothing.
+func (h *hasher) ProcessResumed(p *simgo.Process, event *simgo.Event) {}
+
+// ProcessSuspended does nothing.
+func (h *hasher) ProcessSuspended(p *simgo.Process, event *simgo.Event) {}
+
+// ProcessFinished does nothing.
+func (h *hasher) ProcessFinished(p *simgo.Process) {}
+
+// ConditionTriggered does nothing.
+func (h *hasher) ConditionTriggered(c *simgo.Condition, event *simgo.Event) {}
+
+// hashValue returns the representation of an event value that is hashed.
+func hashValue(val interface{}) string {
+	switch val := val.(type) {
+	case nil, bool, string, int, int8, int16, int32, int64, uint, uint8,
+		uint16, uint32, uint64, float32, float64:
+		return fmt.Sprintf("%#v", val)
+	case error:
+		return fmt.Sprintf("error(%q)", val.Error())
+	default:
+		return fmt.Sprintf("%T", val)
+	}
+}
@@ -0,0 +1,76 @@
+package simtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+)
+
+// recordingT records the errors reported by a test helper.
+type recordingT struct {
+	testing.TB
+	errors   []string
+	cleanups []func()
+}
+
+func (rt *recordingT) Helper() {}
+
+func (rt *recordingT) Cleanup(fn func()) {
+	rt.cleanups = append(rt.cleanups, fn)
+}
+
+// cleanup runs the cleanup functions.
+func (rt *recordingT) cleanup() {
+	for i := len(rt.cleanups) - 1; i >= 0; i-- {
+		rt.cleanups[i]()
+	}
+}
+
+func (rt *recordingT) Errorf(format string, args ...interface{}) {
+	rt.errors = append(rt.errors, fmt.Sprintf(format, args...))
+}
+
+// ticker starts a process that waits for random delays.
+func ticker(env *simgo.Environment, rng *random.Stream, name string) {
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		for i := 0; i < 10; i++ {
+			to := simgo.NewTimeout(env, rng.Delay(random.Exponential(5)), i, simgo.WithName(name))
+			to.Schedule(env)
+			pc.Yield(to.Event)
+		}
+		return nil
+	}, simgo.WithName(name)))
+	p.Init()
+}
+
+func TestAssertDeterministic(t *testing.T) {
+	AssertDeterministic(t, func(env *simgo.Environment, rng *random.Stream) {
+		ticker(env, rng.Named("a"), "a")
+		ticker(env, rng.Named("b"), "b")
+		env.Run(nil)
+	}, 1, Runs(4), VaryGOMAXPROCS())
+}
+
+func TestAssertDeterministicDiverges(t *testing.T) {
+	names := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		names[fmt.Sprintf("ticker-%d", i)] = true
+	}
+	rt := &recordingT{}
+	AssertDeterministic(rt, func(env *simgo.Environment, rng *random.Stream) {
+		// Map iteration order is random.
+		for name := range names {
+			ticker(env, rng.Named(name), name)
+		}
+		env.Run(nil)
+	}, 1, Runs(5))
+	if len(rt.errors) != 1 {
+		t.Fatalf("errors = %q, want: one error", rt.errors)
+	}
+	if !strings.Contains(rt.errors[0], "diverges from run 1 at step") {
+		t.Errorf("error = %q, want: a divergence", rt.errors[0])
+	}
+}
@@ -0,0 +1,72 @@
+package simtest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+	"github.com/bgmerrell/simgo/trace"
+)
+
+// update makes AssertGolden() write the golden files instead of comparing
+// against them.
+var update = flag.Bool("simtest.update", false, "update the golden trace files")
+
+// AssertGolden runs the model with the provided seed and compares its trace,
+// in the trace.JSONLines format, with the golden file testdata/<name>.golden.
+// The error reports the first line that differs.  When the test is run with
+// the -simtest.update flag, the golden file is written instead.
+func AssertGolden(t testing.TB, name string, model Model, seed uint64) {
+	t.Helper()
+	var buf bytes.Buffer
+	env := simgo.NewEnvironment()
+	tw := trace.NewWriter(env, &buf, trace.JSONLines)
+	env.Observer = tw
+	model(env, random.New(seed))
+	if err := tw.Flush(); err != nil {
+		t.Fatalf("cannot write trace: %s", err)
+	}
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatalf("cannot create testdata: %s", err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatalf("cannot update golden file: %s", err)
+		}
+		return
+	}
+	golden, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cannot read golden file (run with -simtest.update to create it): %s", err)
+	}
+	if line, want, got, ok := firstDiff(string(golden), buf.String()); ok {
+		t.Errorf("trace differs from %s at line %d:\n- %s\n+ %s", path, line, want, got)
+	}
+}
+
+// firstDiff returns the first line that differs between want and got, with
+// its (1-based) number.
+func firstDiff(want, got string) (int, string, string, bool) {
+	wantLines := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	gotLines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		w, g := "end of trace", "end of trace"
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g {
+			return i + 1, w, g, true
+		}
+	}
+	return 0, "", "", false
+}
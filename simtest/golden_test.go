@@ -0,0 +1,30 @@
+package simtest
+
+import (
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+)
+
+func TestAssertGolden(t *testing.T) {
+	AssertGolden(t, "tickers", func(env *simgo.Environment, rng *random.Stream) {
+		ticker(env, rng.Named("a"), "a")
+		ticker(env, rng.Named("b"), "b")
+		env.Run(nil)
+	}, 1)
+}
+
+func TestFirstDiff(t *testing.T) {
+	if _, _, _, ok := firstDiff("a\nb\n", "a\nb\n"); ok {
+		t.Errorf("ok = true, want: false for equal traces")
+	}
+	line, want, got, ok := firstDiff("a\nb\n", "a\nc\nd\n")
+	if !ok || line != 2 || want != "b" || got != "c" {
+		t.Errorf("firstDiff() = %d, %q, %q, %t, want: 2, \"b\", \"c\", true", line, want, got, ok)
+	}
+	line, want, got, _ = firstDiff("a\n", "a\nb\n")
+	if line != 2 || want != "end of trace" || got != "b" {
+		t.Errorf("firstDiff() = %d, %q, %q, want: 2, \"end of trace\", \"b\"", line, want, got)
+	}
+}
@@ -0,0 +1,35 @@
+// Package simtest helps test simgo models.
+//
+// AssertGolden runs a model and compares its event trace with a golden file,
+// which is updated by running the tests with the -simtest.update flag.
+// AssertAt and ExpectProcessFinishedBy check the state of a model at a given
+// simulation time:
+//
+//	func TestModel(t *testing.T) {
+//		simtest.AssertGolden(t, "model", func(env *simgo.Environment, rng *random.Stream) {
+//			m := NewQueueModel(env, rng)
+//			simtest.AssertAt(t, env, 100, func() bool { return m.Served() > 0 })
+//			env.Run(1000)
+//		}, 42)
+//	}
+//
+// AssertDeterministic runs a model several times with the same seed and
+// fails the test if the runs do not process the same events with the same
+// values in the same order:
+//
+//	func TestModelIsDeterministic(t *testing.T) {
+//		simtest.AssertDeterministic(t, func(env *simgo.Environment, rng *random.Stream) {
+//			NewQueueModel(env, rng)
+//			env.Run(1000)
+//		}, 42)
+//	}
+package simtest
+
+import (
+	"github.com/bgmerrell/simgo"
+	"github.com/bgmerrell/simgo/random"
+)
+
+// A Model builds a model in the provided Environment and runs it.  All
+// random numbers must be drawn from rng (or streams derived from it).
+type Model func(env *simgo.Environment, rng *random.Stream)
@@ -0,0 +1,187 @@
+package stats
+
+import (
+	"math"
+	"sort"
+
+	"github.com/juju/errgo"
+)
+
+const (
+	// mserBatchSize is the batch size used by MSER5()
+	mserBatchSize = 5
+	// maxBatches is the number of batches BatchMeans() starts with
+	maxBatches = 1024
+	// minBatches is the fewest batches BatchMeans() accepts
+	minBatches = 10
+)
+
+// MSER5 returns the number of initial observations to discard as warm-up
+// using the MSER-5 rule: the observations are averaged in batches of five
+// and the truncation point minimizes the marginal standard error of the
+// remaining batch means.  At most half of the observations are discarded.
+func MSER5(observations []float64) int {
+	k := len(observations) / mserBatchSize
+	if k < 2 {
+		return 0
+	}
+	means := batchMeans(observations[:k*mserBatchSize], mserBatchSize)
+
+	// Accumulate sums from the end so each truncation point is O(1).
+	best, bestStat := 0, math.Inf(1)
+	var sum, sumSq float64
+	stat := make([]float64, k)
+	for j := k - 1; j >= 0; j-- {
+		sum += means[j]
+		sumSq += means[j] * means[j]
+		n := float64(k - j)
+		stat[j] = (sumSq - sum*sum/n) / (n * n)
+	}
+	for d := 0; d <= k/2; d++ {
+		if stat[d] < bestStat {
+			best, bestStat = d, stat[d]
+		}
+	}
+	return best * mserBatchSize
+}
+
+// WelchAverages returns the data for Welch's graphical warm-up procedure: the
+// observations are averaged across replications (up to the length of the
+// shortest one) and smoothed with a moving average over the provided window
+// on each side.  The warm-up ends where the plotted averages level off.
+func WelchAverages(replications [][]float64, window int) []float64 {
+	if len(replications) == 0 {
+		return nil
+	}
+	m := len(replications[0])
+	for _, rep := range replications {
+		if len(rep) < m {
+			m = len(rep)
+		}
+	}
+	avg := make([]float64, m)
+	for i := range avg {
+		for _, rep := range replications {
+			avg[i] += rep[i]
+		}
+		avg[i] /= float64(len(replications))
+	}
+	if m <= window {
+		return nil
+	}
+	smoothed := make([]float64, m-window)
+	for i := range smoothed {
+		w := window
+		if i < window {
+			// Shrink the window near the start.
+			w = i
+		}
+		sum := 0.0
+		for j := i - w; j <= i+w; j++ {
+			sum += avg[j]
+		}
+		smoothed[i] = sum / float64(2*w+1)
+	}
+	return smoothed
+}
+
+// TimeAverages converts a time series of a level into observations by
+// averaging the level over consecutive intervals of the provided width, from
+// start until end.  The level before the first sample is the first sample's
+// value.  The time series of a ResourceMonitor are converted with its
+// InUseSeries() and QueueLengthSeries() methods:
+//
+//	utilization := stats.TimeAverages(m.InUseSeries(), warmup, env.Now, 100)
+func TimeAverages(series []Sample, start, end, width uint64) []float64 {
+	if len(series) == 0 || width == 0 || end <= start {
+		return nil
+	}
+	// levelAt returns the index of the sample in effect at time t.
+	levelAt := func(t uint64) int {
+		i := sort.Search(len(series), func(i int) bool { return series[i].Time > t })
+		if i > 0 {
+			i--
+		}
+		return i
+	}
+	n := (end - start) / width
+	avgs := make([]float64, n)
+	for k := range avgs {
+		from := start + uint64(k)*width
+		to := from + width
+		area := 0.0
+		for i, t := levelAt(from), from; t < to; i++ {
+			next := to
+			if i+1 < len(series) && series[i+1].Time < to {
+				next = series[i+1].Time
+			}
+			area += series[i].Value * float64(next-t)
+			t = next
+		}
+		avgs[k] = area / float64(width)
+	}
+	return avgs
+}
+
+// BatchMeans returns a confidence interval for the steady-state mean of a
+// single long run using non-overlapping batch means.  The batch size is chosen
+// automatically: it is doubled until the lag-1 autocorrelation of the batch
+// means is not significant.  The batch size is returned along with the
+// interval, whose N is the number of batches.
+func BatchMeans(observations []float64, level float64) (Interval, int, error) {
+	size := (len(observations) + maxBatches - 1) / maxBatches
+	if size < 1 {
+		size = 1
+	}
+	for ; len(observations)/size >= minBatches; size *= 2 {
+		k := len(observations) / size
+		means := batchMeans(observations[:k*size], size)
+		if math.Abs(lag1(means)) <= 1.96/math.Sqrt(float64(k)) {
+			return ConfidenceInterval(means, level), size, nil
+		}
+	}
+	return Interval{}, 0, errgo.Newf("%d observations are too few for %d uncorrelated batches", len(observations), minBatches)
+}
+
+// SteadyState returns a confidence interval for the steady-state mean of a
+// single long run.  The warm-up is removed with MSER5() and the rest is
+// analyzed with BatchMeans().  The number of observations discarded as
+// warm-up is returned along with the interval.
+func SteadyState(observations []float64, level float64) (Interval, int, error) {
+	warmup := MSER5(observations)
+	iv, _, err := BatchMeans(observations[warmup:], level)
+	return iv, warmup, err
+}
+
+// batchMeans returns the means of consecutive batches of the provided size.
+func batchMeans(observations []float64, size int) []float64 {
+	means := make([]float64, len(observations)/size)
+	for i := range means {
+		sum := 0.0
+		for _, x := range observations[i*size : (i+1)*size] {
+			sum += x
+		}
+		means[i] = sum / float64(size)
+	}
+	return means
+}
+
+// lag1 returns the lag-1 autocorrelation of xs.
+func lag1(xs []float64) float64 {
+	mean := 0.0
+	for _, x := range xs {
+		mean += x
+	}
+	mean /= float64(len(xs))
+	var num, den float64
+	for i, x := range xs {
+		den += (x - mean) * (x - mean)
+		if i > 0 {
+			num += (x - mean) * (xs[i-1] - mean)
+		}
+	}
+	if den == 0 {
+		return 0
+	}
+	return num / den
+}
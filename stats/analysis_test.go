@@ -0,0 +1,100 @@
+package stats
+
+import (
+	"math"
+	"testing"
+
+	"github.com/bgmerrell/simgo/random"
+)
+
+// ar1 returns n observations of an AR(1) process with the provided mean and
+// autocorrelation, starting from start.
+func ar1(n int, start, mean, phi float64, s *random.Stream) []float64 {
+	noise := random.Normal(0, 1)
+	xs := make([]float64, n)
+	x := start
+	for i := range xs {
+		x = mean + phi*(x-mean) + noise.Sample(s)
+		xs[i] = x
+	}
+	return xs
+}
+
+func TestMSER5(t *testing.T) {
+	xs := ar1(5000, 200, 10, 0.9, random.New(1))
+	warmup := MSER5(xs)
+	// The initial bias decays as 190*0.9^i, which is below 1 after 50
+	// observations.
+	if warmup < 20 || warmup > 500 {
+		t.Errorf("warmup = %d, want: between 20 and 500", warmup)
+	}
+	if warmup%5 != 0 {
+		t.Errorf("warmup = %d, want: a multiple of 5", warmup)
+	}
+}
+
+func TestBatchMeans(t *testing.T) {
+	covered := 0
+	for seed := uint64(0); seed < 20; seed++ {
+		xs := ar1(20000, 10, 10, 0.8, random.New(seed))
+		iv, size, err := BatchMeans(xs, 0.95)
+		if err != nil {
+			t.Fatalf("err = %s, want: nil", err)
+		}
+		if size < 2 {
+			t.Errorf("size = %d, want: at least 2 for correlated data", size)
+		}
+		if math.Abs(iv.Mean-10) <= iv.HalfWidth {
+			covered++
+		}
+	}
+	if covered < 16 {
+		t.Errorf("covered = %d of 20, want: at least 16", covered)
+	}
+	if _, _, err := BatchMeans(make([]float64, 5), 0.95); err == nil {
+		t.Errorf("err = nil, want: non-nil")
+	}
+}
+
+func TestSteadyState(t *testing.T) {
+	xs := ar1(20000, 500, 10, 0.8, random.New(3))
+	iv, warmup, err := SteadyState(xs, 0.95)
+	if err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if warmup == 0 {
+		t.Errorf("warmup = 0, want: > 0")
+	}
+	if math.Abs(iv.Mean-10) > 2*iv.HalfWidth {
+		t.Errorf("iv = %+v, want mean near 10", iv)
+	}
+}
+
+func TestWelchAverages(t *testing.T) {
+	reps := [][]float64{{1, 2, 3, 4, 5, 6}, {3, 4, 5, 6, 7, 8, 9}}
+	got := WelchAverages(reps, 1)
+	// Averages: 2, 3, 4, 5, 6, 7
+	want := []float64{2, 3, 4, 5, 6}
+	if len(got) != len(want) {
+		t.Fatalf("got = %v, want: %v", got, want)
+	}
+	for i := range got {
+		if !closeTo(got[i], want[i], 1e-9) {
+			t.Errorf("got[%d] = %g, want: %g", i, got[i], want[i])
+		}
+	}
+}
+
+func TestTimeAverages(t *testing.T) {
+	series := []Sample{{0, 2}, {3, 4}, {5, 0}}
+	got := TimeAverages(series, 0, 8, 4)
+	want := []float64{(2*3 + 4*1) / 4.0, (4*1 + 0*3) / 4.0}
+	if len(got) != len(want) {
+		t.Fatalf("got = %v, want: %v", got, want)
+	}
+	for i := range got {
+		if !closeTo(got[i], want[i], 1e-9) {
+			t.Errorf("got[%d] = %g, want: %g", i, got[i], want[i])
+		}
+	}
+}
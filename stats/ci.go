@@ -0,0 +1,156 @@
+package stats
+
+import (
+	"math"
+)
+
+// An Interval is a confidence interval for a mean.
+type Interval struct {
+	// Mean is the point estimate
+	Mean float64
+	// HalfWidth is half the width of the interval
+	HalfWidth float64
+	// N is the number of observations the interval is based on
+	N int
+	// Level is the confidence level (e.g., 0.95)
+	Level float64
+}
+
+// Lower returns the lower bound of the interval.
+func (iv Interval) Lower() float64 {
+	return iv.Mean - iv.HalfWidth
+}
+
+// Upper returns the upper bound of the interval.
+func (iv Interval) Upper() float64 {
+	return iv.Mean + iv.HalfWidth
+}
+
+// RelativeHalfWidth returns the half-width relative to the magnitude of the
+// mean (e.g., 0.05 for a mean of 10 +/- 0.5).
+func (iv Interval) RelativeHalfWidth() float64 {
+	return iv.HalfWidth / math.Abs(iv.Mean)
+}
+
+// ConfidenceInterval returns the Student-t confidence interval at the
+// provided level for the mean of independent, identically distributed
+// observations.  The half-width is NaN if there are fewer than two
+// observations.
+func ConfidenceInterval(observations []float64, level float64) Interval {
+	t := NewTally()
+	for _, x := range observations {
+		t.Add(x)
+	}
+	return TallyInterval(t, level)
+}
+
+// TallyInterval returns the Student-t confidence interval at the provided
+// level for the mean of the observations in a Tally.
+func TallyInterval(t *Tally, level float64) Interval {
+	iv := Interval{
+		Mean:      t.Mean(),
+		HalfWidth: math.NaN(),
+		N:         int(t.Count()),
+		Level:     level,
+	}
+	if iv.N >= 2 {
+		q := StudentTQuantile(1-(1-level)/2, float64(iv.N-1))
+		iv.HalfWidth = q * t.StdDev() / math.Sqrt(float64(iv.N))
+	}
+	return iv
+}
+
+// StudentTQuantile returns the p-quantile of Student's t distribution with
+// the provided degrees of freedom.
+func StudentTQuantile(p, df float64) float64 {
+	if p <= 0 || p >= 1 || df <= 0 {
+		return math.NaN()
+	}
+	if p < 0.5 {
+		return -StudentTQuantile(1-p, df)
+	}
+	// Find an upper bound, then bisect on the CDF.
+	lo, hi := 0.0, 1.0
+	for studentTCDF(hi, df) < p {
+		lo, hi = hi, hi*2
+	}
+	for i := 0; i < 200 && hi-lo > 1e-12*hi; i++ {
+		mid := (lo + hi) / 2
+		if studentTCDF(mid, df) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// studentTCDF returns the CDF of Student's t distribution at t >= 0.
+func studentTCDF(t, df float64) float64 {
+	return 1 - 0.5*regIncBeta(df/2, 0.5, df/(df+t*t))
+}
+
+// regIncBeta returns the regularized incomplete beta function I_x(a, b).
+func regIncBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	lga, _ := math.Lgamma(a)
+	lgb, _ := math.Lgamma(b)
+	lgab, _ := math.Lgamma(a + b)
+	front := math.Exp(lgab - lga - lgb + a*math.Log(x) + b*math.Log(1-x))
+	// The continued fraction converges quickly for x < (a+1)/(a+b+2).
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(a, b, x) / a
+	}
+	return 1 - front*betaContinuedFraction(b, a, 1-x)/b
+}
+
+// betaContinuedFraction evaluates the continued fraction for the incomplete
+// beta function using the modified Lentz method.
+func betaContinuedFraction(a, b, x float64) float64 {
+	const (
+		tiny = 1e-300
+		eps  = 1e-15
+	)
+	c, d := 1.0, 1-(a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1.0; m <= 300; m++ {
+		// Even step
+		num := m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		// Odd step
+		num = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		d = 1 + num*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + num/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < eps {
+			break
+		}
+	}
+	return h
+}
@@ -0,0 +1,103 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+)
+
+// A Bin is a histogram bin holding the observations in [Low, High).
+type Bin struct {
+	Low, High float64
+	Count     uint64
+}
+
+// A Histogram counts observations in equal-width bins.  Observations outside
+// of the bins are counted as underflow or overflow, and NaNs are counted
+// apart.
+type Histogram struct {
+	low, high, width float64
+	counts           []uint64
+	underflow        uint64
+	overflow         uint64
+	nan              uint64
+}
+
+// NewHistogram returns a new Histogram with the provided number of bins
+// covering [low, high).  It panics if there are no bins or if high is not
+// above low.
+func NewHistogram(low, high float64, bins int) *Histogram {
+	if bins < 1 || !(high > low) {
+		panic(fmt.Sprintf("stats: histogram needs at least one bin and low < high, got %d bins over [%g, %g)", bins, low, high))
+	}
+	return &Histogram{
+		low:    low,
+		high:   high,
+		width:  (high - low) / float64(bins),
+		counts: make([]uint64, bins),
+	}
+}
+
+// Add adds an observation.
+func (h *Histogram) Add(x float64) {
+	switch {
+	case math.IsNaN(x):
+		h.nan++
+	case x < h.low:
+		h.underflow++
+	case x >= h.high:
+		h.overflow++
+	default:
+		i := int((x - h.low) / h.width)
+		if i >= len(h.counts) {
+			// x is just below high, but rounding put it past the last
+			// bin
+			i = len(h.counts) - 1
+		}
+		h.counts[i]++
+	}
+}
+
+// Bins returns the histogram bins.
+func (h *Histogram) Bins() []Bin {
+	bins := make([]Bin, len(h.counts))
+	for i, count := range h.counts {
+		bins[i] = Bin{
+			Low:   h.low + float64(i)*h.width,
+			High:  h.low + float64(i+1)*h.width,
+			Count: count,
+		}
+	}
+	return bins
+}
+
+// Underflow returns the number of observations below the first bin.
+func (h *Histogram) Underflow() uint64 {
+	return h.underflow
+}
+
+// Overflow returns the number of observations above the last bin.
+func (h *Histogram) Overflow() uint64 {
+	return h.overflow
+}
+
+// NaN returns the number of NaN observations.
+func (h *Histogram) NaN() uint64 {
+	return h.nan
+}
+
+// Count returns the total number of observations, including NaNs.
+func (h *Histogram) Count() uint64 {
+	n := h.underflow + h.overflow + h.nan
+	for _, count := range h.counts {
+		n += count
+	}
+	return n
+}
+
+// Reset discards all observations (e.g., at the end of a warm-up period).
+func (h *Histogram) Reset() {
+	h.counts = make([]uint64, len(h.counts))
+	h.underflow = 0
+	h.overflow = 0
+	h.nan = 0
+}
@@ -0,0 +1,231 @@
+package stats
+
+import (
+	"github.com/bgmerrell/simgo"
+)
+
+// A ResourceSample is the state of a resource at a point in simulated time.
+type ResourceSample struct {
+	Time uint64
+	// InUse is the amount of the resource in use (for a store, the number
+	// of items it holds)
+	InUse float64
+	// QueueLength is the number of requests waiting
+	QueueLength float64
+}
+
+// ResourceStats is a snapshot of the statistics collected by a
+// ResourceMonitor.
+type ResourceStats struct {
+	// Time is the simulation time of the snapshot
+	Time uint64
+	// Duration is the simulated time the statistics cover
+	Duration uint64
+	Capacity float64
+	// Utilization is the time-weighted mean fraction of the capacity in use
+	Utilization float64
+	// MeanQueueLength and MaxQueueLength describe the time-weighted number of
+	// waiting requests
+	MeanQueueLength float64
+	MaxQueueLength  float64
+	// Requests is the number of requests made, including balked ones
+	Requests uint64
+	// Granted is the number of requests granted
+	Granted uint64
+	// Released is the number of requests that are done with the resource
+	Released uint64
+	// Balked is the number of requests that left without being granted
+	Balked uint64
+	// Throughput is the number of released requests per unit of time
+	Throughput float64
+	// Waiting summarizes the time granted requests waited
+	Waiting WaitingStats
+}
+
+// WaitingStats summarizes the distribution of waiting times.
+type WaitingStats struct {
+	Mean, StdDev, Min, Max float64
+	// P50, P90 and P99 are estimated quantiles
+	P50, P90, P99 float64
+}
+
+// waitingAccuracy is the relative accuracy of waiting time quantiles.
+const waitingAccuracy = 0.01
+
+// A ResourceMonitor collects statistics for a shared resource (or store).
+// Instrumentation is opt-in: a resource reports the requests it receives to
+// its monitor, if it has one, and the monitor does the rest using the
+// Environment's current time.  A ResourceMonitor is a
+// simgo.ResourceObserver, so a simgo.Store is instrumented by setting its
+// Observer.
+//
+// The monitor also keeps a time series of the resource state, sampled
+// whenever the state changes.
+type ResourceMonitor struct {
+	env      *simgo.Environment
+	capacity float64
+	inUse    *TimeWeighted
+	queue    *TimeWeighted
+	waits    *Tally
+	sketch   *Sketch
+	// waiting maps waiting requests to the time they were made
+	waiting  map[interface{}]uint64
+	requests uint64
+	granted  uint64
+	released uint64
+	balked   uint64
+	series   []ResourceSample
+}
+
+var _ simgo.ResourceObserver = (*ResourceMonitor)(nil)
+
+// NewResourceMonitor returns a new ResourceMonitor for a resource with the
+// provided capacity.
+func NewResourceMonitor(env *simgo.Environment, capacity float64) *ResourceMonitor {
+	m := &ResourceMonitor{
+		env:      env,
+		capacity: capacity,
+		inUse:    NewTimeWeighted(env, 0),
+		queue:    NewTimeWeighted(env, 0),
+		waits:    NewTally(),
+		sketch:   NewSketch(waitingAccuracy),
+		waiting:  make(map[interface{}]uint64),
+	}
+	m.sample()
+	return m
+}
+
+// Requested records that the provided request was made and is waiting.
+func (m *ResourceMonitor) Requested(req interface{}) {
+	m.requests++
+	m.waiting[req] = m.env.Now
+	m.queue.Add(1)
+	m.sample()
+}
+
+// Granted records that the provided request was granted the provided amount
+// of the resource.  A request that was granted without first being reported
+// as Requested() is counted as a request that did not wait.
+func (m *ResourceMonitor) Granted(req interface{}, amount float64) {
+	requestedAt, ok := m.waiting[req]
+	if ok {
+		delete(m.waiting, req)
+		m.queue.Add(-1)
+	} else {
+		m.requests++
+		requestedAt = m.env.Now
+	}
+	m.granted++
+	wait := float64(m.env.Now - requestedAt)
+	m.waits.Add(wait)
+	m.sketch.Add(wait)
+	m.inUse.Add(amount)
+	m.sample()
+}
+
+// Released records that the provided amount of the resource was released.
+func (m *ResourceMonitor) Released(amount float64) {
+	m.released++
+	m.inUse.Add(-amount)
+	m.sample()
+}
+
+// Balked records that the provided request left without being granted,
+// either immediately or after waiting.
+func (m *ResourceMonitor) Balked(req interface{}) {
+	if _, ok := m.waiting[req]; ok {
+		delete(m.waiting, req)
+		m.queue.Add(-1)
+	} else {
+		m.requests++
+	}
+	m.balked++
+	m.sample()
+}
+
+// Snapshot returns the statistics collected so far.
+func (m *ResourceMonitor) Snapshot() ResourceStats {
+	stats := ResourceStats{
+		Time:            m.env.Now,
+		Duration:        m.inUse.Duration(),
+		Capacity:        m.capacity,
+		MeanQueueLength: m.queue.Mean(),
+		MaxQueueLength:  m.queue.Max(),
+		Requests:        m.requests,
+		Granted:         m.granted,
+		Released:        m.released,
+		Balked:          m.balked,
+		Waiting: WaitingStats{
+			Mean:   m.waits.Mean(),
+			StdDev: m.waits.StdDev(),
+			Min:    m.waits.Min(),
+			Max:    m.waits.Max(),
+			P50:    m.sketch.Quantile(0.5),
+			P90:    m.sketch.Quantile(0.9),
+			P99:    m.sketch.Quantile(0.99),
+		},
+	}
+	if m.capacity > 0 {
+		stats.Utilization = m.inUse.Mean() / m.capacity
+	}
+	if stats.Duration > 0 {
+		stats.Throughput = float64(m.released) / float64(stats.Duration)
+	}
+	return stats
+}
+
+// Series returns the resource state sampled whenever it changed.  When the
+// state changed several times at the same simulation time, only the last
+// state is kept.
+func (m *ResourceMonitor) Series() []ResourceSample {
+	return m.series
+}
+
+// InUseSeries returns the time series of the amount of the resource in use.
+func (m *ResourceMonitor) InUseSeries() []Sample {
+	series := make([]Sample, len(m.series))
+	for i, s := range m.series {
+		series[i] = Sample{s.Time, s.InUse}
+	}
+	return series
+}
+
+// QueueLengthSeries returns the time series of the number of waiting
+// requests.
+func (m *ResourceMonitor) QueueLengthSeries() []Sample {
+	series := make([]Sample, len(m.series))
+	for i, s := range m.series {
+		series[i] = Sample{s.Time, s.QueueLength}
+	}
+	return series
+}
+
+// Reset discards the statistics and time series collected so far (e.g., at
+// the end of a warm-up period).  Requests that are still waiting are
+// counted again once they are granted or balk.
+func (m *ResourceMonitor) Reset() {
+	m.inUse.Reset()
+	m.queue.Reset()
+	m.waits.Reset()
+	m.sketch.Reset()
+	m.requests = uint64(len(m.waiting))
+	m.granted = 0
+	m.released = 0
+	m.balked = 0
+	m.series = nil
+	m.sample()
+}
+
+// sample adds the current state to the time series.
+func (m *ResourceMonitor) sample() {
+	s := ResourceSample{
+		Time:        m.env.Now,
+		InUse:       m.inUse.Value(),
+		QueueLength: m.queue.Value(),
+	}
+	if n := len(m.series); n > 0 && m.series[n-1].Time == s.Time {
+		m.series[n-1] = s
+		return
+	}
+	m.series = append(m.series, s)
+}
@@ -0,0 +1,132 @@
+package stats
+
+import (
+	"math"
+	"sort"
+)
+
+// minSketchValue is the smallest magnitude tracked by a Sketch.  Observations
+// closer to zero are counted as zero.
+const minSketchValue = 1e-9
+
+// A Sketch estimates quantiles of a stream of observations in bounded memory.
+// Estimates are within a relative error (the sketch's accuracy) of the true
+// quantile.
+//
+// Observations are counted in buckets whose boundaries grow geometrically,
+// following the DDSketch algorithm (Masson et al., 2019).
+type Sketch struct {
+	gamma    float64
+	logGamma float64
+	// positive and negative hold the bucket counts for observations above
+	// and below zero (by magnitude)
+	positive map[int]uint64
+	negative map[int]uint64
+	zero     uint64
+	n        uint64
+	min, max float64
+}
+
+// NewSketch returns a new Sketch with the provided relative accuracy (e.g.,
+// 0.01 for quantiles within 1%).
+func NewSketch(accuracy float64) *Sketch {
+	gamma := (1 + accuracy) / (1 - accuracy)
+	s := &Sketch{
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+	}
+	s.Reset()
+	return s
+}
+
+// Add adds an observation.
+func (s *Sketch) Add(x float64) {
+	switch {
+	case x > minSketchValue:
+		s.positive[s.index(x)]++
+	case x < -minSketchValue:
+		s.negative[s.index(-x)]++
+	default:
+		s.zero++
+	}
+	s.n++
+	s.min = math.Min(s.min, x)
+	s.max = math.Max(s.max, x)
+}
+
+// Count returns the number of observations.
+func (s *Sketch) Count() uint64 {
+	return s.n
+}
+
+// Quantile returns an estimate of the q-quantile (0 <= q <= 1) of the
+// observations, or NaN if there are none.
+func (s *Sketch) Quantile(q float64) float64 {
+	if s.n == 0 || q < 0 || q > 1 {
+		return math.NaN()
+	}
+	if q == 0 {
+		return s.min
+	}
+	if q == 1 {
+		return s.max
+	}
+	rank := uint64(q * float64(s.n-1))
+	var seen uint64
+
+	// Negative buckets hold the smallest values at their largest indexes.
+	negIdxs := sortedIndexes(s.negative)
+	for i := len(negIdxs) - 1; i >= 0; i-- {
+		seen += s.negative[negIdxs[i]]
+		if seen > rank {
+			return s.clamp(-s.value(negIdxs[i]))
+		}
+	}
+	seen += s.zero
+	if seen > rank {
+		return 0
+	}
+	for _, idx := range sortedIndexes(s.positive) {
+		seen += s.positive[idx]
+		if seen > rank {
+			return s.clamp(s.value(idx))
+		}
+	}
+	return s.max
+}
+
+// Reset discards all observations (e.g., at the end of a warm-up period).
+func (s *Sketch) Reset() {
+	s.positive = make(map[int]uint64)
+	s.negative = make(map[int]uint64)
+	s.zero = 0
+	s.n = 0
+	s.min = math.Inf(1)
+	s.max = math.Inf(-1)
+}
+
+// index returns the bucket index for the provided positive value.
+func (s *Sketch) index(x float64) int {
+	return int(math.Ceil(math.Log(x) / s.logGamma))
+}
+
+// value returns the representative value of the bucket with the provided
+// index.
+func (s *Sketch) value(idx int) float64 {
+	return 2 * math.Pow(s.gamma, float64(idx)) / (s.gamma + 1)
+}
+
+// clamp limits an estimate to the observed range.
+func (s *Sketch) clamp(x float64) float64 {
+	return math.Max(s.min, math.Min(s.max, x))
+}
+
+// sortedIndexes returns the bucket indexes in ascending order.
+func sortedIndexes(buckets map[int]uint64) []int {
+	idxs := make([]int, 0, len(buckets))
+	for idx := range buckets {
+		idxs = append(idxs, idx)
+	}
+	sort.Ints(idxs)
+	return idxs
+}
@@ -0,0 +1,288 @@
+package stats
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+)
+
+// closeTo returns whether a and b are within tol of each other.
+func closeTo(a, b, tol float64) bool {
+	return math.Abs(a-b) <= tol
+}
+
+func TestTimeWeighted(t *testing.T) {
+	env := simgo.NewEnvironment()
+	tw := NewTimeWeighted(env, 0)
+	tw.Record()
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		// level: 0 for 2, 3 for 4, 1 for 4
+		for _, step := range []struct {
+			delay uint64
+			level float64
+		}{{2, 3}, {4, 1}, {4, 1}} {
+			to := simgo.NewTimeout(env, step.delay, nil)
+			to.Schedule(env)
+			pc.Yield(to.Event)
+			tw.Update(step.level)
+		}
+		return nil
+	}))
+	p.Init()
+	env.Run(nil)
+
+	if got, want := tw.Mean(), (3.0*4+1*4)/10; !closeTo(got, want, 1e-9) {
+		t.Errorf("tw.Mean() = %g, want: %g", got, want)
+	}
+	if got, want := tw.Variance(), (9.0*4+1*4)/10-1.6*1.6; !closeTo(got, want, 1e-9) {
+		t.Errorf("tw.Variance() = %g, want: %g", got, want)
+	}
+	if tw.Min() != 0 || tw.Max() != 3 {
+		t.Errorf("tw.Min(), tw.Max() = %g, %g, want: 0, 3", tw.Min(), tw.Max())
+	}
+
+	// The level does not change at 10.
+	wantSeries := []Sample{{0, 0}, {2, 3}, {6, 1}}
+	if got := tw.Series(); !reflect.DeepEqual(got, wantSeries) {
+		t.Errorf("tw.Series() = %v, want: %v", got, wantSeries)
+	}
+
+	tw.Reset()
+	if got, want := tw.Mean(), 1.0; got != want {
+		t.Errorf("tw.Mean() = %g, want: %g", got, want)
+	}
+}
+
+func TestTally(t *testing.T) {
+	tally := NewTally()
+	for _, x := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		tally.Add(x)
+	}
+	if tally.Count() != 8 {
+		t.Errorf("tally.Count() = %d, want: 8", tally.Count())
+	}
+	if got, want := tally.Mean(), 5.0; got != want {
+		t.Errorf("tally.Mean() = %g, want: %g", got, want)
+	}
+	if got, want := tally.Variance(), 32.0/7; !closeTo(got, want, 1e-9) {
+		t.Errorf("tally.Variance() = %g, want: %g", got, want)
+	}
+	if tally.Min() != 2 || tally.Max() != 9 {
+		t.Errorf("tally.Min(), tally.Max() = %g, %g, want: 2, 9", tally.Min(), tally.Max())
+	}
+	tally.Reset()
+	if !math.IsNaN(tally.Mean()) {
+		t.Errorf("tally.Mean() = %g, want: NaN", tally.Mean())
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram(0, 10, 5)
+	for _, x := range []float64{-1, 0, 1.5, 2, 9.9, 10, 12} {
+		h.Add(x)
+	}
+	wantCounts := []uint64{2, 1, 0, 0, 1}
+	for i, bin := range h.Bins() {
+		if bin.Count != wantCounts[i] {
+			t.Errorf("Bin %d: count = %d, want: %d", i, bin.Count, wantCounts[i])
+		}
+	}
+	if h.Underflow() != 1 || h.Overflow() != 2 {
+		t.Errorf("h.Underflow(), h.Overflow() = %d, %d, want: 1, 2", h.Underflow(), h.Overflow())
+	}
+	if h.Count() != 7 {
+		t.Errorf("h.Count() = %d, want: 7", h.Count())
+	}
+}
+
+func TestHistogramInfNaN(t *testing.T) {
+	h := NewHistogram(0, 10, 5)
+	for _, x := range []float64{math.Inf(-1), -1e300, math.Inf(1), 1e300, math.NaN()} {
+		h.Add(x)
+	}
+	if h.Underflow() != 2 || h.Overflow() != 2 || h.NaN() != 1 {
+		t.Errorf("h.Underflow(), h.Overflow(), h.NaN() = %d, %d, %d, want: 2, 2, 1", h.Underflow(), h.Overflow(), h.NaN())
+	}
+	if h.Count() != 5 {
+		t.Errorf("h.Count() = %d, want: 5", h.Count())
+	}
+}
+
+func TestNewHistogramPanics(t *testing.T) {
+	for _, test := range []struct {
+		low, high float64
+		bins      int
+	}{{0, 10, 0}, {0, 10, -1}, {10, 10, 5}, {10, 0, 5}, {math.NaN(), 10, 5}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewHistogram(%g, %g, %d) did not panic", test.low, test.high, test.bins)
+				}
+			}()
+			NewHistogram(test.low, test.high, test.bins)
+		}()
+	}
+}
+
+func TestSketch(t *testing.T) {
+	const accuracy = 0.01
+	s := NewSketch(accuracy)
+	for i := -500; i <= 1000; i++ {
+		s.Add(float64(i))
+	}
+	for _, test := range []struct {
+		q, want float64
+	}{
+		{0, -500},
+		{0.1, -350},
+		{0.5, 250},
+		{0.9, 850},
+		{1, 1000},
+	} {
+		got := s.Quantile(test.q)
+		if !closeTo(got, test.want, math.Abs(test.want)*accuracy+1) {
+			t.Errorf("s.Quantile(%g) = %g, want: %g", test.q, got, test.want)
+		}
+	}
+}
+
+func TestResourceMonitor(t *testing.T) {
+	env := simgo.NewEnvironment()
+	m := NewResourceMonitor(env, 1)
+	wait := func(pc *simgo.ProcComm, delay uint64) {
+		to := simgo.NewTimeout(env, delay, nil)
+		to.Schedule(env)
+		pc.Yield(to.Event)
+	}
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		// "a" is granted immediately and holds the resource for 4, while
+		// "b" waits for it and "c" balks.
+		m.Requested("a")
+		m.Granted("a", 1)
+		m.Requested("b")
+		m.Balked("c")
+		wait(pc, 4)
+		m.Released(1)
+		m.Granted("b", 1)
+		wait(pc, 2)
+		m.Released(1)
+		wait(pc, 2)
+		return nil
+	}))
+	p.Init()
+	env.Run(nil)
+
+	stats := m.Snapshot()
+	if stats.Utilization != 0.75 {
+		t.Errorf("stats.Utilization = %g, want: 0.75", stats.Utilization)
+	}
+	if stats.MeanQueueLength != 0.5 || stats.MaxQueueLength != 1 {
+		t.Errorf("stats.MeanQueueLength, stats.MaxQueueLength = %g, %g, want: 0.5, 1",
+			stats.MeanQueueLength, stats.MaxQueueLength)
+	}
+	if stats.Requests != 3 || stats.Granted != 2 || stats.Released != 2 || stats.Balked != 1 {
+		t.Errorf("stats = %+v, want: 3 requests, 2 granted, 2 released, 1 balked", stats)
+	}
+	if stats.Throughput != 0.25 {
+		t.Errorf("stats.Throughput = %g, want: 0.25", stats.Throughput)
+	}
+	if stats.Waiting.Mean != 2 || stats.Waiting.Max != 4 {
+		t.Errorf("stats.Waiting = %+v, want: mean 2, max 4", stats.Waiting)
+	}
+
+	wantSeries := []ResourceSample{{0, 1, 1}, {4, 1, 0}, {6, 0, 0}}
+	series := m.Series()
+	if len(series) != len(wantSeries) {
+		t.Fatalf("series = %+v, want: %+v", series, wantSeries)
+	}
+	for i := range series {
+		if series[i] != wantSeries[i] {
+			t.Errorf("series[%d] = %+v, want: %+v", i, series[i], wantSeries[i])
+		}
+	}
+
+	// The series are analyzed as levels.
+	for _, test := range []struct {
+		name   string
+		series []Sample
+		want   []float64
+	}{
+		{"in use", m.InUseSeries(), []float64{1, 0.5}},
+		{"queue length", m.QueueLengthSeries(), []float64{1, 0}},
+	} {
+		got := TimeAverages(test.series, 0, 8, 4)
+		if len(got) != 2 || got[0] != test.want[0] || got[1] != test.want[1] {
+			t.Errorf("TimeAverages(%s) = %v, want: %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestStoreMonitor(t *testing.T) {
+	env := simgo.NewEnvironment()
+	store := simgo.NewStore(env, 1)
+	m := NewResourceMonitor(env, 1)
+	store.Observer = m
+	wait := func(pc *simgo.ProcComm, delay uint64) {
+		to := simgo.NewTimeout(env, delay, nil)
+		to.Schedule(env)
+		pc.Yield(to.Event)
+	}
+	simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		// The second item waits for room until 4.
+		pc.Yield(store.Put("a"))
+		pc.Yield(store.Put("b"))
+		return nil
+	})).Init()
+	simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		wait(pc, 4)
+		pc.Yield(store.Get())
+		pc.Yield(store.Get())
+		// The third get waits until it gives up at 8.
+		get := store.Get()
+		wait(pc, 4)
+		store.Cancel(get)
+		return nil
+	})).Init()
+	env.Run(nil)
+
+	stats := m.Snapshot()
+	if stats.Requests != 5 || stats.Granted != 4 || stats.Released != 2 || stats.Balked != 1 {
+		t.Errorf("stats = %+v, want: 5 requests, 4 granted, 2 released, 1 balked", stats)
+	}
+	if stats.Utilization != 0.5 || stats.MaxQueueLength != 1 {
+		t.Errorf("stats.Utilization, stats.MaxQueueLength = %g, %g, want: 0.5, 1", stats.Utilization, stats.MaxQueueLength)
+	}
+	if stats.Waiting.Max != 4 {
+		t.Errorf("stats.Waiting.Max = %g, want: 4", stats.Waiting.Max)
+	}
+}
+
+func TestStudentTQuantile(t *testing.T) {
+	for _, test := range []struct {
+		p, df, want float64
+	}{
+		{0.975, 1, 12.7062},
+		{0.975, 4, 2.7764},
+		{0.95, 9, 1.8331},
+		{0.995, 29, 2.7564},
+		{0.025, 10, -2.2281},
+		{0.975, 1000, 1.9623},
+	} {
+		if got := StudentTQuantile(test.p, test.df); !closeTo(got, test.want, 1e-4) {
+			t.Errorf("StudentTQuantile(%g, %g) = %g, want: %g", test.p, test.df, got, test.want)
+		}
+	}
+}
+
+func TestConfidenceInterval(t *testing.T) {
+	iv := ConfidenceInterval([]float64{2, 4, 4, 4, 5, 5, 7, 9}, 0.95)
+	if iv.Mean != 5 || iv.N != 8 {
+		t.Errorf("iv.Mean, iv.N = %g, %d, want: 5, 8", iv.Mean, iv.N)
+	}
+	// t(0.975, 7) * s / sqrt(n)
+	if want := 2.36462 * math.Sqrt(32.0/7) / math.Sqrt(8); !closeTo(iv.HalfWidth, want, 1e-4) {
+		t.Errorf("iv.HalfWidth = %g, want: %g", iv.HalfWidth, want)
+	}
+}
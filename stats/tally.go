@@ -0,0 +1,91 @@
+package stats
+
+import (
+	"math"
+)
+
+// A Tally collects observations (e.g., waiting times) and computes their
+// statistics without storing them.
+type Tally struct {
+	n    uint64
+	mean float64
+	// m2 is the sum of squared differences from the mean
+	m2       float64
+	min, max float64
+	// recording is whether observations are kept
+	recording    bool
+	observations []float64
+}
+
+// NewTally returns a new, empty Tally.
+func NewTally() *Tally {
+	t := &Tally{}
+	t.Reset()
+	return t
+}
+
+// Add adds an observation.
+func (t *Tally) Add(x float64) {
+	// Welford's algorithm
+	t.n++
+	delta := x - t.mean
+	t.mean += delta / float64(t.n)
+	t.m2 += delta * (x - t.mean)
+	t.min = math.Min(t.min, x)
+	t.max = math.Max(t.max, x)
+	if t.recording {
+		t.observations = append(t.observations, x)
+	}
+}
+
+// Record starts keeping the observations that are added, in order.
+func (t *Tally) Record() {
+	t.recording = true
+}
+
+// Observations returns the observations kept since Record() was called.
+func (t *Tally) Observations() []float64 {
+	return t.observations
+}
+
+// Count returns the number of observations.
+func (t *Tally) Count() uint64 {
+	return t.n
+}
+
+// Mean returns the mean of the observations, or NaN if there are none.
+func (t *Tally) Mean() float64 {
+	if t.n == 0 {
+		return math.NaN()
+	}
+	return t.mean
+}
+
+// Variance returns the sample variance of the observations, or NaN if there
+// are fewer than two.
+func (t *Tally) Variance() float64 {
+	if t.n < 2 {
+		return math.NaN()
+	}
+	return t.m2 / float64(t.n-1)
+}
+
+// StdDev returns the sample standard deviation of the observations.
+func (t *Tally) StdDev() float64 {
+	return math.Sqrt(t.Variance())
+}
+
+// Min returns the smallest observation, or +Inf if there are none.
+func (t *Tally) Min() float64 {
+	return t.min
+}
+
+// Max returns the largest observation, or -Inf if there are none.
+func (t *Tally) Max() float64 {
+	return t.max
+}
+
+// Reset discards all observations (e.g., at the end of a warm-up period).
+func (t *Tally) Reset() {
+	*t = Tally{min: math.Inf(1), max: math.Inf(-1), recording: t.recording}
+}
@@ -0,0 +1,170 @@
+// Package stats provides monitors that collect statistics from simgo
+// simulations.
+//
+// A TimeWeighted monitor integrates a level (e.g., a queue length or the
+// number of busy servers) over simulated time.  A Tally collects
+// observations (e.g., waiting times) and a Histogram and Sketch summarize
+// their distribution.  Every monitor can be Reset() at the end of a warm-up
+// period.
+//
+// The time series and observations recorded by monitors can be analyzed to
+// find the end of the warm-up period (MSER5(), WelchAverages()) and to
+// estimate steady-state means from a single long run (BatchMeans(),
+// SteadyState()).
+package stats
+
+import (
+	"math"
+
+	"github.com/bgmerrell/simgo"
+)
+
+// A TimeWeighted monitor tracks a level that changes over simulated time and
+// computes its time-weighted statistics.
+type TimeWeighted struct {
+	env *simgo.Environment
+	// start is the time the monitor was created or last reset
+	start uint64
+	// last is the time of the last update
+	last uint64
+	// value is the current level
+	value float64
+	// area is the integral of the level from start to last
+	area float64
+	// area2 is the integral of the squared level from start to last
+	area2    float64
+	min, max float64
+	// recording is whether the level is recorded as a time series
+	recording bool
+	series    []Sample
+}
+
+// A Sample is the value of a level at a point in simulated time.
+type Sample struct {
+	Time  uint64
+	Value float64
+}
+
+// NewTimeWeighted returns a new TimeWeighted monitor with the provided initial
+// level, starting at the current simulation time.
+func NewTimeWeighted(env *simgo.Environment, initial float64) *TimeWeighted {
+	tw := &TimeWeighted{env: env, value: initial}
+	tw.Reset()
+	return tw
+}
+
+// Update sets the level at the current simulation time.
+func (tw *TimeWeighted) Update(value float64) {
+	tw.integrate()
+	tw.value = value
+	tw.min = math.Min(tw.min, value)
+	tw.max = math.Max(tw.max, value)
+	if tw.recording {
+		tw.sample()
+	}
+}
+
+// Add changes the level by the provided delta at the current simulation time.
+func (tw *TimeWeighted) Add(delta float64) {
+	tw.Update(tw.value + delta)
+}
+
+// Value returns the current level.
+func (tw *TimeWeighted) Value() float64 {
+	return tw.value
+}
+
+// Duration returns the simulated time the statistics have been collected for.
+func (tw *TimeWeighted) Duration() uint64 {
+	return tw.env.Now - tw.start
+}
+
+// Mean returns the time-weighted mean of the level up to the current
+// simulation time.  If no time has passed, the current level is returned.
+func (tw *TimeWeighted) Mean() float64 {
+	area, _ := tw.areas()
+	if tw.Duration() == 0 {
+		return tw.value
+	}
+	return area / float64(tw.Duration())
+}
+
+// Variance returns the time-weighted variance of the level up to the current
+// simulation time.
+func (tw *TimeWeighted) Variance() float64 {
+	if tw.Duration() == 0 {
+		return 0
+	}
+	mean := tw.Mean()
+	_, area2 := tw.areas()
+	return math.Max(area2/float64(tw.Duration())-mean*mean, 0)
+}
+
+// Record starts recording the level as a time series that is sampled
+// whenever the level changes.
+func (tw *TimeWeighted) Record() {
+	if !tw.recording {
+		tw.recording = true
+		tw.sample()
+	}
+}
+
+// Series returns the recorded time series.  When the level changed several
+// times at the same simulation time, only the last level is kept.
+func (tw *TimeWeighted) Series() []Sample {
+	return tw.series
+}
+
+// Min returns the minimum level.
+func (tw *TimeWeighted) Min() float64 {
+	return tw.min
+}
+
+// Max returns the maximum level.
+func (tw *TimeWeighted) Max() float64 {
+	return tw.max
+}
+
+// Reset discards the statistics collected so far (e.g., at the end of a
+// warm-up period).  The current level is kept.
+func (tw *TimeWeighted) Reset() {
+	tw.start = tw.env.Now
+	tw.last = tw.env.Now
+	tw.area = 0
+	tw.area2 = 0
+	tw.min = tw.value
+	tw.max = tw.value
+	tw.series = nil
+	if tw.recording {
+		tw.sample()
+	}
+}
+
+// sample adds the current level to the time series, unless it is the level
+// of the previous sample.
+func (tw *TimeWeighted) sample() {
+	s := Sample{tw.env.Now, tw.value}
+	n := len(tw.series)
+	if n > 0 && tw.series[n-1].Time == s.Time {
+		// The level changed again at the same time.
+		tw.series = tw.series[:n-1]
+		n--
+	}
+	if n > 0 && tw.series[n-1].Value == s.Value {
+		return
+	}
+	tw.series = append(tw.series, s)
+}
+
+// integrate adds the area under the level since the last update.
+func (tw *TimeWeighted) integrate() {
+	tw.area, tw.area2 = tw.areas()
+	tw.last = tw.env.Now
+}
+
+// areas returns the integrals of the level and the squared level up to the
+// current simulation time.
+func (tw *TimeWeighted) areas() (float64, float64) {
+	dt := float64(tw.env.Now - tw.last)
+	return tw.area + tw.value*dt, tw.area2 + tw.value*tw.value*dt
+}
@@ -0,0 +1,191 @@
+package simgo
+
+import (
+	"encoding/json"
+
+	"github.com/juju/errgo"
+)
+
+// A ResourceObserver is notified of the requests made to a resource, e.g., to
+// collect statistics with a stats.ResourceMonitor.  Requests are identified
+// by their events.
+type ResourceObserver interface {
+	// Requested is called when a request has to wait.
+	Requested(req interface{})
+	// Granted is called when a request is served, and amount units of the
+	// resource are used from now on.
+	Granted(req interface{}, amount float64)
+	// Released is called when amount units of the resource are no longer
+	// used.
+	Released(amount float64)
+	// Balked is called when a request is withdrawn.
+	Balked(req interface{})
+}
+
+// A Store holds items in FIFO order, like a simpy Store.  Processes put items
+// into the store and get items out of it by yielding the events returned by
+// Put() and Get(), which are triggered once the item has been stored or
+// retrieved.  Pending requests are served in the order they were made.
+//
+// Statistics are collected by setting Store.Observer: the number of items is
+// the amount of the resource in use, so a Put() is granted one unit once its
+// item is stored, and a Get() is granted no unit and then releases one once
+// it has retrieved its item.  Requests that wait for room or for an item are
+// the queue.
+type Store struct {
+	// Observer, if set, is notified of the requests to the store
+	Observer ResourceObserver
+
+	env *Environment
+	// capacity is the maximum number of items, or 0 for no limit
+	capacity int
+	items    []interface{}
+	puts     []storePut
+	gets     []*Event
+}
+
+// storePut is a pending Put() request.
+type storePut struct {
+	event *Event
+	item  interface{}
+}
+
+// NewStore returns a new Store that holds up to capacity items, or any number
+// of items if capacity is 0.
+func NewStore(env *Environment, capacity int) *Store {
+	return &Store{env: env, capacity: capacity}
+}
+
+// Put returns an event that is triggered once the item has been put into the
+// store, which is immediate unless the store is full.
+func (s *Store) Put(item interface{}) *Event {
+	e := NewEvent(s.env, WithName("Store.Put"))
+	s.puts = append(s.puts, storePut{e, item})
+	s.dispatch()
+	s.requested(e)
+	return e
+}
+
+// Get returns an event that is triggered with the first item of the store
+// once there is one.
+func (s *Store) Get() *Event {
+	e := NewEvent(s.env, WithName("Store.Get"))
+	s.gets = append(s.gets, e)
+	s.dispatch()
+	s.requested(e)
+	return e
+}
+
+// Cancel withdraws a pending Put() or Get() request (e.g., after a timeout)
+// and returns whether it was pending.
+func (s *Store) Cancel(e *Event) bool {
+	for i, put := range s.puts {
+		if put.event == e {
+			s.puts = append(s.puts[:i], s.puts[i+1:]...)
+			s.balked(e)
+			return true
+		}
+	}
+	for i, get := range s.gets {
+		if get == e {
+			s.gets = append(s.gets[:i], s.gets[i+1:]...)
+			s.balked(e)
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of items in the store.
+func (s *Store) Len() int {
+	return len(s.items)
+}
+
+// Items returns a copy of the items in the store, in order.
+func (s *Store) Items() []interface{} {
+	return append([]interface{}(nil), s.items...)
+}
+
+// dispatch serves the pending requests that can be served.
+func (s *Store) dispatch() {
+	for progress := true; progress; {
+		progress = false
+		for len(s.puts) > 0 && (s.capacity == 0 || len(s.items) < s.capacity) {
+			put := s.puts[0]
+			s.puts = s.puts[1:]
+			s.items = append(s.items, put.item)
+			if s.Observer != nil {
+				s.Observer.Granted(put.event, 1)
+			}
+			put.event.Succeed(nil)
+			progress = true
+		}
+		for len(s.gets) > 0 && len(s.items) > 0 {
+			get := s.gets[0]
+			s.gets = s.gets[1:]
+			item := s.items[0]
+			s.items = s.items[1:]
+			if s.Observer != nil {
+				s.Observer.Granted(get, 0)
+				s.Observer.Released(1)
+			}
+			get.Succeed(item)
+			progress = true
+		}
+	}
+}
+
+// requested notifies the observer of a request that has to wait.
+func (s *Store) requested(e *Event) {
+	if s.Observer != nil && !e.Triggered() {
+		s.Observer.Requested(e)
+	}
+}
+
+// balked notifies the observer of a withdrawn request.
+func (s *Store) balked(e *Event) {
+	if s.Observer != nil {
+		s.Observer.Balked(e)
+	}
+}
+
+// Snapshot returns the items in the store encoded as JSON, to be included in
+// the snapshot of a model (see Snapshotter).  Pending requests are not
+// included: the processes that made them are rebuilt when the model is
+// restored, and make them again.
+func (s *Store) Snapshot() ([]byte, error) {
+	data, err := json.Marshal(s.items)
+	if err != nil {
+		return nil, errgo.Notef(err, "cannot snapshot store")
+	}
+	return data, nil
+}
+
+// Restore sets the items of a new store from a snapshot returned by
+// Snapshot(), without triggering any event, e.g., in a RestoreFunc before the
+// processes that use the store are rebuilt.  Each item is decoded by decode,
+// or as a generic JSON value if decode is nil.  Returns an error if the store
+// already has items or pending requests.
+func (s *Store) Restore(snapshot []byte, decode func(json.RawMessage) (interface{}, error)) error {
+	if len(s.items) > 0 || len(s.puts) > 0 || len(s.gets) > 0 {
+		return errgo.New("cannot restore a store that is in use")
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(snapshot, &raw); err != nil {
+		return errgo.Notef(err, "cannot decode store snapshot")
+	}
+	items := make([]interface{}, len(raw))
+	for i, data := range raw {
+		var err error
+		if decode != nil {
+			items[i], err = decode(data)
+		} else {
+			err = json.Unmarshal(data, &items[i])
+		}
+		if err != nil {
+			return errgo.Notef(err, "cannot decode store item %d", i)
+		}
+	}
+	s.items = items
+	return nil
+}
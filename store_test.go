@@ -0,0 +1,195 @@
+package simgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	env := NewEnvironment()
+	s := NewStore(env, 2)
+	var log []interface{}
+	producer := NewProcess(env, ProcWrapper(env, func(env *Environment, pc *ProcComm) interface{} {
+		for i := 0; i < 4; i++ {
+			pc.Yield(s.Put(i))
+			log = append(log, "put", i, env.Now)
+		}
+		return nil
+	}))
+	consumer := NewProcess(env, ProcWrapper(env, func(env *Environment, pc *ProcComm) interface{} {
+		for i := 0; i < 4; i++ {
+			to := NewTimeout(env, 5, nil)
+			to.Schedule(env)
+			pc.Yield(to.Event)
+			item := pc.Yield(s.Get())
+			log = append(log, "get", item, env.Now)
+		}
+		return nil
+	}))
+	producer.Init()
+	consumer.Init()
+	env.Run(nil)
+	// The store is full after two items, so the producer waits for the
+	// consumer.
+	want := []interface{}{
+		"put", 0, uint64(0), "put", 1, uint64(0),
+		"get", 0, uint64(5), "put", 2, uint64(5),
+		"get", 1, uint64(10), "put", 3, uint64(10),
+		"get", 2, uint64(15), "get", 3, uint64(20),
+	}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("log = %v, want: %v", log, want)
+	}
+	if s.Len() != 0 {
+		t.Errorf("s.Len() = %d, want: 0", s.Len())
+	}
+}
+
+func TestStoreCancel(t *testing.T) {
+	env := NewEnvironment()
+	s := NewStore(env, 0)
+	get := s.Get()
+	if !s.Cancel(get) {
+		t.Errorf("s.Cancel() = false, want: true for a pending get")
+	}
+	s.Put("spam")
+	env.Run(nil)
+	if get.Triggered() {
+		t.Errorf("get.Triggered() = true, want: false after Cancel()")
+	}
+	if got := s.Items(); !reflect.DeepEqual(got, []interface{}{"spam"}) {
+		t.Errorf("s.Items() = %v, want: [spam]", got)
+	}
+	if s.Cancel(get) {
+		t.Errorf("s.Cancel() = true, want: false for a cancelled get")
+	}
+}
+
+// storeModel has a producer that puts an item into a store every 10 units of
+// time, and a consumer that gets the items and takes the provided time to
+// consume each one.
+type storeModel struct {
+	env      *Environment
+	store    *Store
+	consume  uint64
+	produced int
+	// consuming is whether the consumer is consuming an item rather than
+	// waiting for one
+	consuming bool
+	log       []string
+}
+
+// storeSnapshot is the snapshot of a storeModel.
+type storeSnapshot struct {
+	Store     json.RawMessage
+	Produced  int
+	Consuming bool
+}
+
+// start starts the producer and the consumer.  Restored processes are
+// already waiting for their events.
+func (m *storeModel) start(restored bool) {
+	producerRestored, consumerRestored := restored, restored
+	NewProcess(m.env, ProcWrapper(m.env, func(env *Environment, pc *ProcComm) interface{} {
+		for {
+			var delay uint64 = 10
+			if producerRestored {
+				delay = 0
+			}
+			producerRestored = false
+			to := NewTimeout(env, delay, nil, WithName("produce"))
+			to.Schedule(env)
+			pc.Yield(to.Event)
+			pc.Yield(m.store.Put(m.produced))
+			m.produced++
+		}
+	})).Init()
+	NewProcess(m.env, ProcWrapper(m.env, func(env *Environment, pc *ProcComm) interface{} {
+		for {
+			if !consumerRestored || !m.consuming {
+				item := pc.Yield(m.store.Get())
+				m.log = append(m.log, fmt.Sprintf("%v@%d", item, env.Now))
+			}
+			delay := m.consume
+			if consumerRestored {
+				delay = 0
+			}
+			consumerRestored = false
+			m.consuming = true
+			to := NewTimeout(env, delay, nil, WithName("consume"))
+			to.Schedule(env)
+			pc.Yield(to.Event)
+			m.consuming = false
+		}
+	})).Init()
+}
+
+func (m *storeModel) Snapshot() ([]byte, error) {
+	store, err := m.store.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(storeSnapshot{store, m.produced, m.consuming})
+}
+
+func TestStoreCheckpoint(t *testing.T) {
+	// The consumer is slower than the producer, so items pile up in the
+	// store, or faster, so it waits for items.
+	for _, consume := range []uint64{25, 5} {
+		env := NewEnvironment()
+		m := &storeModel{env: env, store: NewStore(env, 0), consume: consume}
+		m.start(false)
+		env.Run(uint64(57))
+		var buf bytes.Buffer
+		if err := env.Checkpoint(&buf, m); err != nil {
+			t.Fatalf("consume %d: err = %s, want: nil", consume, err)
+		}
+		logged := len(m.log)
+		env.Run(uint64(200))
+
+		var restored *storeModel
+		renv, err := Restore(&buf, func(env *Environment, snapshot []byte) error {
+			var snap storeSnapshot
+			if err := json.Unmarshal(snapshot, &snap); err != nil {
+				return err
+			}
+			restored = &storeModel{
+				env:       env,
+				store:     NewStore(env, 0),
+				consume:   consume,
+				produced:  snap.Produced,
+				consuming: snap.Consuming,
+			}
+			err := restored.store.Restore(snap.Store, func(data json.RawMessage) (interface{}, error) {
+				var item int
+				err := json.Unmarshal(data, &item)
+				return item, err
+			})
+			if err != nil {
+				return err
+			}
+			restored.start(true)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("consume %d: err = %s, want: nil", consume, err)
+		}
+		renv.Run(uint64(200))
+		if !reflect.DeepEqual(restored.log, m.log[logged:]) {
+			t.Errorf("consume %d: restored.log = %v, want: %v", consume, restored.log, m.log[logged:])
+		}
+		if !reflect.DeepEqual(restored.store.Items(), m.store.Items()) {
+			t.Errorf("consume %d: restored items = %v, want: %v", consume, restored.store.Items(), m.store.Items())
+		}
+	}
+
+	env := NewEnvironment()
+	s := NewStore(env, 0)
+	s.Get()
+	if err := s.Restore([]byte("[1]"), nil); err == nil {
+		t.Errorf("s.Restore() = nil, want: an error for a store in use")
+	}
+}
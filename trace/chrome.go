@@ -0,0 +1,225 @@
+package trace
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/bgmerrell/simgo"
+)
+
+// chromePID is the process ID used for all Chrome trace events.  The whole
+// simulation is shown as a single Chrome "process" with one track (thread)
+// per simgo Process.
+const chromePID = 1
+
+// chromeEvent is an event in the Chrome Trace Event format.
+type chromeEvent struct {
+	Name string                 `json:"name"`
+	Cat  string                 `json:"cat,omitempty"`
+	Ph   string                 `json:"ph"`
+	Ts   uint64                 `json:"ts"`
+	Dur  *uint64                `json:"dur,omitempty"`
+	Pid  int                    `json:"pid"`
+	Tid  int                    `json:"tid"`
+	ID   string                 `json:"id,omitempty"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+// chromeTrack is the state of a process track.
+type chromeTrack struct {
+	tid     int
+	started uint64
+	// suspendedOn is the event the process is waiting for, if any
+	suspendedOn *simgo.Event
+	suspendedAt uint64
+	// label describes the event the process is waiting for
+	label string
+}
+
+// A ChromeExporter records the lifetimes of processes and exports them in the
+// Chrome Trace Event format, which can be loaded into Perfetto or
+// chrome://tracing.  Set it as the Environment's Observer to enable it, and
+// call WriteTo() once the simulation is done.
+//
+// Each process becomes a track with a slice covering its lifetime.  Each
+// interval between a process yielding an event and being resumed becomes a
+// nested slice labeled with the yielded event (as it was when yielded).  One
+// unit of simulation time is shown as one microsecond.
+//
+// Holds on shared resources can be shown as async spans using BeginSpan() and
+// EndSpan().
+type ChromeExporter struct {
+	env    *simgo.Environment
+	names  processNames
+	tracks map[*simgo.Process]*chromeTrack
+	// nextTID is the thread ID given to the next process track
+	nextTID int
+	events  []chromeEvent
+}
+
+var _ simgo.Observer = (*ChromeExporter)(nil)
+
+// NewChromeExporter returns a new ChromeExporter for the provided
+// Environment.
+func NewChromeExporter(env *simgo.Environment) *ChromeExporter {
+	return &ChromeExporter{
+		env:     env,
+		names:   make(processNames),
+		tracks:  make(map[*simgo.Process]*chromeTrack),
+		nextTID: 1,
+	}
+}
+
+// BeginSpan starts an async span (e.g., a resource hold) in the provided
+// category.  The span is shown on its own track, identified by the category,
+// name and ID, until the matching EndSpan() call.
+func (ce *ChromeExporter) BeginSpan(cat, name string, id uint64) {
+	ce.asyncEvent("b", cat, name, id)
+}
+
+// EndSpan ends an async span started with BeginSpan().
+func (ce *ChromeExporter) EndSpan(cat, name string, id uint64) {
+	ce.asyncEvent("e", cat, name, id)
+}
+
+// WriteTo writes the Chrome trace JSON to w.  Processes that are still
+// running (or waiting) are shown as running until the current simulation
+// time.
+func (ce *ChromeExporter) WriteTo(w io.Writer) (int64, error) {
+	events := append([]chromeEvent(nil), ce.events...)
+	running := make([]*simgo.Process, 0, len(ce.tracks))
+	for p := range ce.tracks {
+		running = append(running, p)
+	}
+	sort.Slice(running, func(i, j int) bool {
+		return ce.tracks[running[i]].tid < ce.tracks[running[j]].tid
+	})
+	for _, p := range running {
+		track := ce.tracks[p]
+		if track.suspendedOn != nil {
+			events = append(events, ce.suspension(track))
+		}
+		events = append(events, ce.lifetime(p, track))
+	}
+	cw := &countingWriter{w: w}
+	err := json.NewEncoder(cw).Encode(struct {
+		TraceEvents     []chromeEvent `json:"traceEvents"`
+		DisplayTimeUnit string        `json:"displayTimeUnit"`
+	}{events, "ms"})
+	return cw.n, err
+}
+
+// EventScheduled does nothing.
+func (ce *ChromeExporter) EventScheduled(event *simgo.Event, time uint64, priority int, eid simgo.EventID) {
+}
+
+// EventProcessed does nothing.
+func (ce *ChromeExporter) EventProcessed(event *simgo.Event, time uint64, priority int, eid simgo.EventID) {
+}
+
+// ProcessStarted adds a track for the process.
+func (ce *ChromeExporter) ProcessStarted(p *simgo.Process) {
+	track := &chromeTrack{
+		tid:     ce.nextTID,
+		started: ce.env.Now,
+	}
+	ce.nextTID++
+	ce.tracks[p] = track
+	ce.events = append(ce.events, chromeEvent{
+		Name: "thread_name",
+		Ph:   "M",
+		Pid:  chromePID,
+		Tid:  track.tid,
+		Args: map[string]interface{}{"name": ce.names.name(p)},
+	})
+}
+
+// ProcessResumed ends the slice for the event the process waited for.
+func (ce *ChromeExporter) ProcessResumed(p *simgo.Process, event *simgo.Event) {
+	track := ce.tracks[p]
+	if track == nil || track.suspendedOn == nil {
+		return
+	}
+	ce.events = append(ce.events, ce.suspension(track))
+	track.suspendedOn = nil
+}
+
+// ProcessSuspended starts a slice for the event the process waits for.
+func (ce *ChromeExporter) ProcessSuspended(p *simgo.Process, event *simgo.Event) {
+	track := ce.tracks[p]
+	if track == nil {
+		return
+	}
+	track.suspendedOn = event
+	track.suspendedAt = ce.env.Now
+	track.label = event.String()
+}
+
+// ProcessFinished adds the lifetime slice of the process.
+func (ce *ChromeExporter) ProcessFinished(p *simgo.Process) {
+	track := ce.tracks[p]
+	if track == nil {
+		return
+	}
+	ce.events = append(ce.events, ce.lifetime(p, track))
+	delete(ce.tracks, p)
+}
+
+// ConditionTriggered does nothing.
+func (ce *ChromeExporter) ConditionTriggered(c *simgo.Condition, event *simgo.Event) {}
+
+// suspension returns the slice for the event the track's process waits for,
+// ending at the current simulation time.
+func (ce *ChromeExporter) suspension(track *chromeTrack) chromeEvent {
+	dur := ce.env.Now - track.suspendedAt
+	return chromeEvent{
+		Name: track.label,
+		Cat:  "yield",
+		Ph:   "X",
+		Ts:   track.suspendedAt,
+		Dur:  &dur,
+		Pid:  chromePID,
+		Tid:  track.tid,
+	}
+}
+
+// lifetime returns the slice for the lifetime of the process, ending at the
+// current simulation time.
+func (ce *ChromeExporter) lifetime(p *simgo.Process, track *chromeTrack) chromeEvent {
+	dur := ce.env.Now - track.started
+	return chromeEvent{
+		Name: ce.names.name(p),
+		Cat:  "process",
+		Ph:   "X",
+		Ts:   track.started,
+		Dur:  &dur,
+		Pid:  chromePID,
+		Tid:  track.tid,
+	}
+}
+
+// asyncEvent adds an async event of the provided phase.
+func (ce *ChromeExporter) asyncEvent(ph, cat, name string, id uint64) {
+	ce.events = append(ce.events, chromeEvent{
+		Name: name,
+		Cat:  cat,
+		Ph:   ph,
+		Ts:   ce.env.Now,
+		Pid:  chromePID,
+		ID:   strconv.FormatUint(id, 10),
+	})
+}
+
+// countingWriter counts the bytes written to the underlying writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,60 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+)
+
+func TestChromeExporter(t *testing.T) {
+	env := simgo.NewEnvironment()
+	ce := NewChromeExporter(env)
+	env.Observer = ce
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		ce.BeginSpan("resource", "hold", 0)
+		to := simgo.NewTimeout(env, 5, nil)
+		to.Schedule(env)
+		pc.Yield(to.Event)
+		ce.EndSpan("resource", "hold", 0)
+		return nil
+	}))
+	p.Init()
+	if _, err := env.Run(nil); err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := ce.WriteTo(&buf); err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	var got struct {
+		TraceEvents []chromeEvent `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	want := []struct {
+		name, ph string
+		ts, dur  uint64
+	}{
+		{"thread_name", "M", 0, 0},
+		{"hold", "b", 0, 0},
+		{"Timeout at 5 (triggered)", "X", 0, 5},
+		{"hold", "e", 5, 0},
+		{"process-1", "X", 0, 5},
+	}
+	if len(got.TraceEvents) != len(want) {
+		t.Fatalf("len(got.TraceEvents) = %d, want: %d", len(got.TraceEvents), len(want))
+	}
+	for i, ev := range got.TraceEvents {
+		var dur uint64
+		if ev.Dur != nil {
+			dur = *ev.Dur
+		}
+		if ev.Name != want[i].name || ev.Ph != want[i].ph || ev.Ts != want[i].ts || dur != want[i].dur {
+			t.Errorf("Test %d: event = %+v, want: %+v", i+1, ev, want[i])
+		}
+	}
+}
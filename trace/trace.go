@@ -0,0 +1,218 @@
+// Package trace records simgo simulation runs.
+//
+// A Writer is a simgo.Observer that writes a record for every event processed
+// by an Environment, which makes it easy to load a simulation run into tools
+// such as pandas or DuckDB:
+//
+//	env := simgo.NewEnvironment()
+//	tw := trace.NewWriter(env, os.Stdout, trace.JSONLines)
+//	env.Observer = tw
+//	env.Run(nil)
+//	tw.Flush()
+package trace
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/bgmerrell/simgo"
+)
+
+// Format is a trace output format.
+type Format int
+
+const (
+	// JSONLines writes one JSON object per line.
+	JSONLines Format = iota
+	// CSV writes comma-separated values with a header row.
+	CSV
+)
+
+// csvHeader is the header row written for the CSV format.
+var csvHeader = []string{"time", "eid", "priority", "kind", "name", "process", "value"}
+
+// A Record describes a single processed event.
+type Record struct {
+	// Time is the simulation time the event was processed at
+	Time uint64 `json:"time"`
+	// EID is the ID the event was scheduled with
+	EID simgo.EventID `json:"eid"`
+	// Priority is the priority the event was scheduled with
+	Priority int `json:"priority"`
+	// Kind is the kind of event (e.g., Timeout, Process or Condition)
+	Kind simgo.EventKind `json:"kind"`
+	// Name is the name of the event, if any
+	Name string `json:"name"`
+	// Process is the name of the process that owns the event, if any
+	Process string `json:"process"`
+	// Value is the value of the event
+	Value interface{} `json:"value"`
+}
+
+// A Writer writes a Record for every event processed by an Environment.
+// Set it as the Environment's Observer to enable it, and call Flush() once
+// the simulation is done.
+//
+// An event is owned by the process that was active when it was scheduled,
+// except for Initialize events which are owned by the process they start.
+type Writer struct {
+	env    *simgo.Environment
+	format Format
+	enc    *json.Encoder
+	csv    *csv.Writer
+	// owners maps scheduled events to the process that owns them
+	owners map[simgo.EventID]*simgo.Process
+	names  processNames
+	// pending is the record of the event that is currently being
+	// processed.  It is written once processing is done, since the owner
+	// of an Initialize event is only known when the process starts.
+	pending *Record
+	err     error
+}
+
+var _ simgo.Observer = (*Writer)(nil)
+
+// NewWriter returns a new Writer that writes records in the provided format
+// to w.
+func NewWriter(env *simgo.Environment, w io.Writer, format Format) *Writer {
+	tw := &Writer{
+		env:    env,
+		format: format,
+		owners: make(map[simgo.EventID]*simgo.Process),
+		names:  make(processNames),
+	}
+	switch format {
+	case CSV:
+		tw.csv = csv.NewWriter(w)
+		tw.err = tw.csv.Write(csvHeader)
+	default:
+		tw.enc = json.NewEncoder(w)
+	}
+	return tw
+}
+
+// Flush writes any buffered records and returns the first error
+// encountered while writing, if any.
+func (tw *Writer) Flush() error {
+	tw.writePending()
+	if tw.csv != nil {
+		tw.csv.Flush()
+		if tw.err == nil {
+			tw.err = tw.csv.Error()
+		}
+	}
+	return tw.err
+}
+
+// EventScheduled remembers the owner of the scheduled event.
+func (tw *Writer) EventScheduled(event *simgo.Event, time uint64, priority int, eid simgo.EventID) {
+	if tw.env.ActiveProcess != nil {
+		tw.owners[eid] = tw.env.ActiveProcess
+	}
+}
+
+// EventProcessed starts a new record for the processed event.
+func (tw *Writer) EventProcessed(event *simgo.Event, time uint64, priority int, eid simgo.EventID) {
+	tw.writePending()
+	owner := tw.owners[eid]
+	delete(tw.owners, eid)
+	val, _ := event.Value()
+	tw.pending = &Record{
+		Time:     time,
+		EID:      eid,
+		Priority: priority,
+		Kind:     event.Kind(),
+		Name:     event.Name(),
+		Process:  tw.names.name(owner),
+		Value:    val,
+	}
+}
+
+// ProcessStarted sets the owner of the Initialize event being processed.
+func (tw *Writer) ProcessStarted(p *simgo.Process) {
+	if tw.pending != nil && tw.pending.Kind == simgo.KindInitialize {
+		tw.pending.Process = tw.names.name(p)
+	}
+}
+
+// ProcessResumed does nothing.
+func (tw *Writer) ProcessResumed(p *simgo.Process, event *simgo.Event) {}
+
+// ProcessSuspended does nothing.
+func (tw *Writer) ProcessSuspended(p *simgo.Process, event *simgo.Event) {}
+
+// ProcessFinished does nothing.
+func (tw *Writer) ProcessFinished(p *simgo.Process) {}
+
+// ConditionTriggered does nothing.
+func (tw *Writer) ConditionTriggered(c *simgo.Condition, event *simgo.Event) {}
+
+// writePending writes the pending record, if any.
+func (tw *Writer) writePending() {
+	if tw.pending == nil || tw.err != nil {
+		tw.pending = nil
+		return
+	}
+	r := tw.pending
+	tw.pending = nil
+	if tw.csv != nil {
+		tw.err = tw.csv.Write([]string{
+			strconv.FormatUint(r.Time, 10),
+			strconv.FormatUint(uint64(r.EID), 10),
+			strconv.Itoa(r.Priority),
+			string(r.Kind),
+			r.Name,
+			r.Process,
+			formatValue(r.Value),
+		})
+		return
+	}
+	r.Value = jsonValue(r.Value)
+	tw.err = tw.enc.Encode(r)
+}
+
+// formatValue formats an event value for a CSV field.
+func formatValue(val interface{}) string {
+	if val == nil {
+		return ""
+	}
+	return fmt.Sprint(val)
+}
+
+// jsonValue returns val if it is a basic JSON value, otherwise it returns
+// val formatted as a string.
+func jsonValue(val interface{}) interface{} {
+	switch val := val.(type) {
+	case nil, bool, string, int, int8, int16, int32, int64, uint, uint8,
+		uint16, uint32, uint64, float32, float64:
+		return val
+	case error:
+		return val.Error()
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// processNames holds the names given to processes.
+type processNames map[*simgo.Process]string
+
+// name returns the name of the provided process, or an empty string if the
+// process is nil.  Processes without a name are named in the order they are
+// first seen.
+func (names processNames) name(p *simgo.Process) string {
+	if p == nil {
+		return ""
+	}
+	if p.Name() != "" {
+		return p.Name()
+	}
+	name, ok := names[p]
+	if !ok {
+		name = fmt.Sprintf("process-%d", len(names)+1)
+		names[p] = name
+	}
+	return name
+}
@@ -0,0 +1,52 @@
+package trace
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bgmerrell/simgo"
+)
+
+// runModel runs a small model with a Writer in the provided format and
+// returns what was written.
+func runModel(t *testing.T, format Format) string {
+	env := simgo.NewEnvironment()
+	var buf bytes.Buffer
+	tw := NewWriter(env, &buf, format)
+	env.Observer = tw
+	p := simgo.NewProcess(env, simgo.ProcWrapper(env, func(env *simgo.Environment, pc *simgo.ProcComm) interface{} {
+		to := simgo.NewTimeout(env, 5, "spam", simgo.WithName("wait"))
+		to.Schedule(env)
+		pc.Yield(to.Event)
+		return nil
+	}, simgo.WithName("worker")))
+	p.Init()
+	if _, err := env.Run(nil); err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	if err := tw.Flush(); err != nil {
+		t.Fatalf("err = %s, want: nil", err)
+	}
+	return buf.String()
+}
+
+func TestWriterJSONLines(t *testing.T) {
+	want := `{"time":0,"eid":1,"priority":0,"kind":"Initialize","name":"worker","process":"worker","value":null}
+{"time":5,"eid":2,"priority":1,"kind":"Timeout","name":"wait","process":"worker","value":"spam"}
+{"time":5,"eid":3,"priority":1,"kind":"Process","name":"worker","process":"worker","value":null}
+`
+	if got := runModel(t, JSONLines); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriterCSV(t *testing.T) {
+	want := `time,eid,priority,kind,name,process,value
+0,1,0,Initialize,worker,worker,
+5,2,1,Timeout,wait,worker,spam
+5,3,1,Process,worker,worker,
+`
+	if got := runModel(t, CSV); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}